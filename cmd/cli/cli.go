@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	simulation "github.com/derrandz/xtinvasion/pkg"
 	"github.com/spf13/cobra"
@@ -17,14 +19,65 @@ func main() {
 	var startCmd = &cobra.Command{
 		Use:   "start",
 		Short: "Start the simulation",
-		Run:   app.Start,
+		RunE:  app.Start,
+
+		// the errors of a run are not usage errors
+		SilenceUsage: true,
+	}
+
+	// Add a replay command
+	var replayCmd = &cobra.Command{
+		Use:   "replay",
+		Short: "Replay a simulation from its journal",
+		RunE:  app.StartReplay,
+	}
+
+	// Add a batch command
+	var batchCmd = &cobra.Command{
+		Use:   "batch",
+		Short: "Run many independent simulations and report aggregate statistics",
+		RunE:  app.StartBatch,
+	}
+
+	// Add a validate command
+	var validateCmd = &cobra.Command{
+		Use:   "validate <map file>...",
+		Short: "Check map files and report every issue with its location",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  app.StartValidate,
+
+		// the diagnostics are more useful than the usage
+		SilenceUsage: true,
+	}
+
+	// Add an analyze command
+	var analyzeCmd = &cobra.Command{
+		Use:   "analyze",
+		Short: "Report the connectivity of a map and the cities whose destruction splits it",
+		RunE:  app.StartAnalyze,
+	}
+
+	// Add a generate command
+	var generateCmd = &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a map: grid with holes, random graph, tree, ring or continents",
+		RunE:  app.StartGenerate,
 	}
 
 	// Define flags
 	app.DefineFlags(startCmd)
-	rootCmd.AddCommand(startCmd)
+	app.DefineReplayFlags(replayCmd)
+	app.DefineBatchFlags(batchCmd)
+	app.DefineValidateFlags(validateCmd)
+	app.DefineAnalyzeFlags(analyzeCmd)
+	app.DefineGenerateFlags(generateCmd)
+	rootCmd.AddCommand(startCmd, replayCmd, batchCmd, validateCmd, analyzeCmd, generateCmd)
+
+	// Cancel the simulation on interrupt, the partial result is still saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+)
+
+const (
+	mapCellWidth = 14 // city name and alien count
+	mapGapWidth  = 3  // horizontal road between two cells
+)
+
+var (
+	roadStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	cityStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	invadedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+	contestedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+	destroyedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("88")).Strikethrough(true)
+)
+
+// mapPanel draws the world map on the grid computed by simulation.ComputeLayout
+type mapPanel struct {
+	layout simulation.Layout
+	err    error
+	state  *simulation.AppState // last broadcast state, nil until the first one
+}
+
+// newMapPanel lays the map out, it must be called before any city is destroyed
+// so that the destroyed cities keep their position
+func newMapPanel(worldMap *simulation.Map) mapPanel {
+	layout, err := simulation.ComputeLayout(worldMap)
+	return mapPanel{layout: layout, err: err}
+}
+
+// update records the state to draw
+func (p *mapPanel) update(state simulation.AppState) {
+	p.state = &state
+}
+
+// roads returns the grid cells crossed by a road and the gaps holding one,
+// gaps are indexed by the cell west (horizontal) or north (vertical) of them
+func (p mapPanel) roads() (hGaps, vGaps, hCrossed, vCrossed map[simulation.GridPoint]bool) {
+	hGaps, vGaps = make(map[simulation.GridPoint]bool), make(map[simulation.GridPoint]bool)
+	hCrossed, vCrossed = make(map[simulation.GridPoint]bool), make(map[simulation.GridPoint]bool)
+	if p.state == nil {
+		return
+	}
+
+	for name, city := range p.state.WorldMap.Cities {
+		from, found := p.layout.Positions[name]
+		if !found {
+			continue
+		}
+		for direction, neighbour := range city.Neighbours {
+			to, found := p.layout.Positions[neighbour.Name]
+			if !found {
+				continue
+			}
+			a, b := from, to
+			switch direction {
+			case "east", "west":
+				if b.X < a.X {
+					a, b = b, a
+				}
+				for x := a.X; x < b.X; x++ {
+					hGaps[simulation.GridPoint{X: x, Y: a.Y}] = true
+					if x > a.X {
+						hCrossed[simulation.GridPoint{X: x, Y: a.Y}] = true
+					}
+				}
+			case "north", "south":
+				if b.Y < a.Y {
+					a, b = b, a
+				}
+				for y := a.Y; y < b.Y; y++ {
+					vGaps[simulation.GridPoint{X: a.X, Y: y}] = true
+					if y > a.Y {
+						vCrossed[simulation.GridPoint{X: a.X, Y: y}] = true
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// cityLabel renders a city with its number of aliens, or crossed out once destroyed
+func (p mapPanel) cityLabel(name string, roadEast bool) string {
+	city, alive := p.state.WorldMap.Cities[name]
+
+	label, style := name, cityStyle
+	switch {
+	case !alive:
+		label, style = "x "+name, destroyedStyle
+	case len(p.state.AlienLocations[city]) == 1:
+		label, style = fmt.Sprintf("%s 1", name), invadedStyle
+	case len(p.state.AlienLocations[city]) > 1:
+		label, style = fmt.Sprintf("%s %d", name, len(p.state.AlienLocations[city])), contestedStyle
+	}
+	if len(label) > mapCellWidth {
+		label = label[:mapCellWidth-1] + "~"
+	}
+
+	fill := " "
+	if roadEast {
+		fill = "─"
+	}
+	return style.Render(label) + roadStyle.Render(strings.Repeat(fill, mapCellWidth-len(label)))
+}
+
+// View renders the map, one line per row of cities and one per row of vertical roads
+func (p mapPanel) View() string {
+	if p.err != nil {
+		return fmt.Sprintf("Map\n\n%v", p.err)
+	}
+	if p.state == nil {
+		return "Map\n\nWaiting for the simulation..."
+	}
+
+	hGaps, vGaps, hCrossed, vCrossed := p.roads()
+	grid := p.layout.Grid()
+
+	var b strings.Builder
+	b.WriteString("Map\n\n")
+	for y, row := range grid {
+		for x, name := range row {
+			at := simulation.GridPoint{X: x, Y: y}
+			switch {
+			case name != "":
+				b.WriteString(p.cityLabel(name, hGaps[at]))
+			case hCrossed[at] && vCrossed[at]:
+				b.WriteString(roadStyle.Render("┼" + strings.Repeat("─", mapCellWidth-1)))
+			case hCrossed[at]:
+				b.WriteString(roadStyle.Render(strings.Repeat("─", mapCellWidth)))
+			case vCrossed[at]:
+				b.WriteString(roadStyle.Render("│" + strings.Repeat(" ", mapCellWidth-1)))
+			default:
+				b.WriteString(strings.Repeat(" ", mapCellWidth))
+			}
+
+			if hGaps[at] {
+				b.WriteString(roadStyle.Render(strings.Repeat("─", mapGapWidth)))
+			} else {
+				b.WriteString(strings.Repeat(" ", mapGapWidth))
+			}
+		}
+		b.WriteString("\n")
+
+		if y == len(grid)-1 {
+			break
+		}
+		for x := range row {
+			if vGaps[simulation.GridPoint{X: x, Y: y}] {
+				b.WriteString(roadStyle.Render("│") + strings.Repeat(" ", mapCellWidth-1+mapGapWidth))
+			} else {
+				b.WriteString(strings.Repeat(" ", mapCellWidth+mapGapWidth))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(invadedStyle.Render("1 alien") + "  " + contestedStyle.Render("2+ aliens") + "  " + destroyedStyle.Render("x destroyed"))
+	return b.String()
+}
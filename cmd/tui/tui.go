@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
@@ -11,7 +13,6 @@ import (
 	"github.com/spf13/cobra"
 
 	simulation "github.com/derrandz/xtinvasion/pkg"
-	"github.com/derrandz/xtinvasion/pkg/logger"
 )
 
 var baseStyle = lipgloss.NewStyle().
@@ -32,42 +33,68 @@ func awaitStateUpdates(sub <-chan simulation.AppState) tea.Cmd {
 	}
 }
 
-func awaitActivityUpdates(sub <-chan string) tea.Cmd {
+func awaitEvents(sub <-chan simulation.Event) tea.Cmd {
 	return func() tea.Msg {
-		return <-sub
+		e, ok := <-sub
+		if !ok {
+			return nil
+		}
+		return e
 	}
 }
 
-// ChannelWriter is an implementation of io.Writer that sends bytes to a channel.
-type ChannelWriter struct {
-	ch chan string
-}
+type model struct {
+	aliensTable    table.Model
+	defendersTable table.Model
+	citiesTable    table.Model
+	activityTable  table.Model
+	mapPanel       mapPanel
 
-// NewChannelWriter creates a new ChannelWriter with the given channel.
-func NewChannelWriter(ch chan string) *ChannelWriter {
-	return &ChannelWriter{
-		ch: ch,
-	}
-}
+	sub chan simulation.AppState
+
+	events <-chan simulation.Event
 
-// Write writes bytes to the channel.
-func (w *ChannelWriter) Write(p []byte) (n int, err error) {
-	w.ch <- string(p)
-	return len(p), nil
+	app *simulation.App
+
+	restarted chan struct{} // signals the run goroutine that the simulation has been restarted
 }
 
-func (w *ChannelWriter) Chan() <-chan string {
-	return w.ch
+// controlMsg reports the outcome of a control key
+type controlMsg string
+
+// restartSimulation restarts the simulation with a new random seed
+// and signals the run goroutine to run it
+func restartSimulation(app *simulation.App, restarted chan<- struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if err := app.Restart(0); err != nil {
+			return controlMsg(fmt.Sprintf("Error restarting simulation: %v", err))
+		}
+		select {
+		case restarted <- struct{}{}:
+		default:
+		}
+		return controlMsg(fmt.Sprintf("Simulation restarted with seed %d", app.Cfg.Seed))
+	}
 }
 
-type model struct {
-	aliensTable   table.Model
-	citiesTable   table.Model
-	activityTable table.Model
+// snapshotMsg reports a snapshot dumped on request
+type snapshotMsg struct {
+	filename string
+	err      error
+}
 
-	sub chan simulation.AppState
+// dumpSnapshot dumps a snapshot of the simulation next to the output map
+func dumpSnapshot(app *simulation.App) tea.Cmd {
+	return func() tea.Msg {
+		filename := filepath.Join(filepath.Dir(app.Cfg.MapOutputFile), "snapshot.json")
+		file, err := os.Create(filename)
+		if err != nil {
+			return snapshotMsg{filename, err}
+		}
+		defer file.Close()
 
-	activityCh chan string
+		return snapshotMsg{filename, app.RequestSnapshot(file)}
+	}
 }
 
 func isAlienTrapped(alien *simulation.Alien) string {
@@ -81,18 +108,47 @@ func isAlienTrapped(alien *simulation.Alien) string {
 func (m *model) handleStateUpdate(msg tea.Msg) {
 	appState := msg.(simulation.AppState)
 
-	// Update aliens table
+	// Update aliens table, the factions show their survivors out of their landed aliens
+	survivors := make(map[string]int)
+	for _, alien := range appState.Aliens {
+		survivors[alien.Faction]++
+	}
 	newAlienRows := make([]table.Row, 0)
 	for _, alien := range appState.Aliens {
+		city := alien.CurrentCity.Name
+		if alien.InTransit() {
+			city = "-> " + city
+		}
+		faction := ""
+		if alien.Faction != "" {
+			faction = fmt.Sprintf("%s (%d/%d)", alien.Faction, survivors[alien.Faction], appState.FactionLanded[alien.Faction])
+		}
 		newAlienRows = append(newAlienRows, table.Row{
 			fmt.Sprintf("%d", len(newAlienRows)+1),
-			alien.CurrentCity.Name,
+			city,
 			fmt.Sprintf("%d", alien.Moved),
 			isAlienTrapped(alien),
+			faction,
 		})
 	}
 	m.aliensTable.SetRows(newAlienRows)
 
+	// Update defenders table
+	newDefenderRows := make([]table.Row, 0)
+	for _, id := range appState.Defenders.IDs() {
+		defender := appState.Defenders[id]
+		city := defender.CurrentCity.Name
+		if defender.InTransit() {
+			city = "-> " + city
+		}
+		newDefenderRows = append(newDefenderRows, table.Row{
+			fmt.Sprintf("%d", id),
+			city,
+			fmt.Sprintf("%d", defender.Moved),
+		})
+	}
+	m.defendersTable.SetRows(newDefenderRows)
+
 	// Update cities table
 	newCityRows := make([]table.Row, 0)
 	for _, city := range appState.WorldMap.Cities {
@@ -125,7 +181,7 @@ func (m *model) handleActivityUpdate(msg string) {
 
 func (m model) Init() tea.Cmd {
 	return tea.Batch(
-		awaitActivityUpdates(m.activityCh),
+		awaitEvents(m.events),
 		tea.Batch(awaitStateUpdates(m.sub), tickCmd()),
 	)
 }
@@ -138,6 +194,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			if m.aliensTable.Focused() {
 				m.aliensTable.Blur()
+				m.defendersTable.Focus()
+			} else if m.defendersTable.Focused() {
+				m.defendersTable.Blur()
 				m.citiesTable.Focus()
 			} else if m.citiesTable.Focused() {
 				m.aliensTable.Blur()
@@ -152,6 +211,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "s":
+			return m, dumpSnapshot(m.app)
+		case "p", " ":
+			if m.app.IsPaused() {
+				m.app.Resume()
+				m.handleActivityUpdate("Simulation resumed")
+			} else {
+				m.app.Pause()
+				m.handleActivityUpdate("Simulation paused, press n to advance a single tick")
+			}
+			return m, cmd
+		case "n":
+			if m.app.IsPaused() {
+				m.app.RequestStep()
+			}
+			return m, cmd
+		case "+":
+			m.app.SetDelay(m.app.Delay() / 2)
+			m.handleActivityUpdate(fmt.Sprintf("Delay set to %d ms", m.app.Delay()))
+			return m, cmd
+		case "-":
+			delay := m.app.Delay() * 2
+			if delay == 0 {
+				delay = 10
+			}
+			m.app.SetDelay(delay)
+			m.handleActivityUpdate(fmt.Sprintf("Delay set to %d ms", m.app.Delay()))
+			return m, cmd
+		case "r":
+			return m, restartSimulation(m.app, m.restarted)
 		case "enter":
 			return m, tea.Batch(
 				tea.Printf("Let's go to %s!", m.aliensTable.SelectedRow()[1]),
@@ -160,15 +249,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case simulation.AppState:
 		m.handleStateUpdate(msg)
+		m.mapPanel.update(msg)
 		return m, tea.Batch(cmd, tea.Batch(awaitStateUpdates(m.sub), tickCmd()))
 
-	case string:
-		m.handleActivityUpdate(msg)
-		return m, tea.Batch(cmd, awaitActivityUpdates(m.activityCh))
+	case simulation.Event:
+		if msg.Type != simulation.EventTickEnded {
+			m.handleActivityUpdate(msg.String())
+		}
+		return m, tea.Batch(cmd, awaitEvents(m.events))
+
+	case controlMsg:
+		m.handleActivityUpdate(string(msg))
+		return m, cmd
+
+	case snapshotMsg:
+		if msg.err != nil {
+			m.handleActivityUpdate(fmt.Sprintf("Error dumping snapshot: %v", msg.err))
+		} else {
+			m.handleActivityUpdate(fmt.Sprintf("Snapshot dumped to %s, resume it with --resume %s", msg.filename, msg.filename))
+		}
+		return m, cmd
 	}
 
 	if m.aliensTable.Focused() {
 		m.aliensTable, cmd = m.aliensTable.Update(msg)
+	} else if m.defendersTable.Focused() {
+		m.defendersTable, cmd = m.defendersTable.Update(msg)
 	} else if m.citiesTable.Focused() {
 		m.citiesTable, cmd = m.citiesTable.Update(msg)
 	} else {
@@ -182,18 +288,14 @@ func (m model) View() string {
 	return lipgloss.JoinVertical(
 		lipgloss.Top,
 		lipgloss.JoinHorizontal(
-			lipgloss.Left,
+			lipgloss.Top,
 			lipgloss.JoinVertical(
 				lipgloss.Left,
 				baseStyle.Render(m.aliensTable.View()),
-			),
-		),
-		lipgloss.JoinHorizontal(
-			lipgloss.Left,
-			lipgloss.JoinVertical(
-				lipgloss.Right,
+				baseStyle.Render(m.defendersTable.View()),
 				baseStyle.Render(m.citiesTable.View()),
 			),
+			baseStyle.Render(m.mapPanel.View()),
 		),
 		lipgloss.JoinHorizontal(
 			lipgloss.Bottom,
@@ -209,9 +311,17 @@ func runTUI(app *simulation.App) func(*cobra.Command, []string) {
 			{Title: "Current City", Width: 15},
 			{Title: "Moved", Width: 8},
 			{Title: "Is Trapped ?", Width: 20},
+			{Title: "Faction", Width: 16},
 		}
 		aliensRows := []table.Row{}
 
+		defendersColumns := []table.Column{
+			{Title: "ID", Width: 10},
+			{Title: "Current City", Width: 15},
+			{Title: "Moved", Width: 8},
+		}
+		defendersRows := []table.Row{}
+
 		citiesColumns := []table.Column{
 			{Title: "ID", Width: 10},
 			{Title: "City", Width: 10},
@@ -232,6 +342,12 @@ func runTUI(app *simulation.App) func(*cobra.Command, []string) {
 			table.WithHeight(7),
 		)
 
+		dt := table.New(
+			table.WithColumns(defendersColumns),
+			table.WithRows(defendersRows),
+			table.WithHeight(4),
+		)
+
 		ct := table.New(
 			table.WithColumns(citiesColumns),
 			table.WithRows(citiesRows),
@@ -257,41 +373,61 @@ func runTUI(app *simulation.App) func(*cobra.Command, []string) {
 			Background(lipgloss.Color("57")).
 			Bold(false)
 		at.SetStyles(s)
+		dt.SetStyles(s)
 		ct.SetStyles(s)
 		act.SetStyles(s)
 
+		// Subscribe before the initialization to show the landings
+		events, cancel, err := app.Subscribe(simulation.SubscribeOptions{Policy: simulation.PolicyRingBuffer, Buffer: 100})
+		if err != nil {
+			fmt.Println("Error subscribing to the simulation:", err)
+			os.Exit(1)
+		}
+		defer cancel()
+
 		sub := make(chan simulation.AppState)
 		m := model{
 			at,
+			dt,
 			ct,
 			act,
+			mapPanel{},
 			sub,
-			make(chan string),
+			events,
+			app,
+			make(chan struct{}, 1),
 		}
 
+		if err := app.Init(cmd); err != nil {
+			fmt.Println("Error initializing simulation:", err)
+			os.Exit(1)
+		}
+
+		// Lay the map out before any city is destroyed
+		m.mapPanel = newMapPanel(app.State.WorldMap)
+
 		go func() {
-			activityWriter := NewChannelWriter(m.activityCh)
-			activityPrinter := logger.NewLogger(activityWriter)
-			app.Init(cmd)
-			app.StateController().SetPrinter(activityPrinter)
-			app.Run()
-
-			fmt.Println()
-			fmt.Println()
-			fmt.Println("----")
-			fmt.Println("Simulation ended")
-			fmt.Println("Result:", app.StateController().SimulationResult())
-			fmt.Println("Press q or ctrl+c to exit")
-			fmt.Println()
+			for {
+				res, _ := app.Run(context.Background())
+
+				fmt.Println()
+				fmt.Println()
+				fmt.Println("----")
+				fmt.Println("Simulation ended")
+				fmt.Println("Result:", res.Reason)
+				fmt.Println("Press r to restart with a new seed, q or ctrl+c to exit")
+				fmt.Println()
+
+				<-m.restarted
+			}
 		}()
 
+		// The state channel outlives restarts, read it once as restarts replace the state controller
+		states := app.StateController().ListenForStateUpdates()
 		go func() {
 			<-app.Ready()
-			for {
-				select {
-				case state := <-app.StateController().ListenForStateUpdates():
-					sub <- state
-				}
+			for state := range states {
+				sub <- state
 			}
 		}()
 
@@ -301,6 +437,10 @@ func runTUI(app *simulation.App) func(*cobra.Command, []string) {
 		fmt.Println()
 		fmt.Println("To toggle between tables, press ESC")
 		fmt.Println("To navigate between rows, use arrow keys")
+		fmt.Println("To dump a snapshot of the simulation, press s")
+		fmt.Println("To pause or resume the simulation, press p or space, and n to advance a single tick while paused")
+		fmt.Println("To speed the simulation up or slow it down, press + or -")
+		fmt.Println("To restart the simulation with a new seed, press r")
 		fmt.Println("To exit, press q or ctrl+c")
 		fmt.Println()
 
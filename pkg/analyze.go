@@ -0,0 +1,309 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// CityFall lists the cities cut off when a city is destroyed
+type CityFall struct {
+	City        string   `json:"city" yaml:"city"`
+	Unreachable []string `json:"unreachable" yaml:"unreachable"` // in map order
+}
+
+// MapAnalysis describes the structure of a map, see AnalyzeMap.
+// Roads are considered both ways, cities are listed in map order.
+type MapAnalysis struct {
+	Cities             int         `json:"cities" yaml:"cities"`
+	Roads              int         `json:"roads" yaml:"roads"`
+	Components         [][]string  `json:"components" yaml:"components"`                   // connected components, largest first
+	ArticulationPoints []string    `json:"articulation_points" yaml:"articulation_points"` // cities whose destruction splits their component
+	Degrees            map[int]int `json:"degrees" yaml:"degrees"`                         // number of roads -> number of cities
+	Diameter           int         `json:"diameter" yaml:"diameter"`                       // longest shortest path in roads, within a component
+	Isolated           []string    `json:"isolated" yaml:"isolated"`                       // cities without roads
+	DeadEnds           []string    `json:"dead_ends" yaml:"dead_ends"`                     // cities with a single road
+	Falls              []CityFall  `json:"falls" yaml:"falls"`                             // for every articulation point
+}
+
+// mapGraph is the undirected graph of the roads of a map, cities are indexed in map order
+type mapGraph struct {
+	names []string
+	adj   [][]int // sorted neighbour indexes
+}
+
+// newMapGraph builds the undirected graph of a map
+func newMapGraph(m *Map) mapGraph {
+	g := mapGraph{names: m.OrderedCityNames()}
+	index := make(map[string]int, len(g.names))
+	for i, name := range g.names {
+		index[name] = i
+	}
+
+	sets := make([]map[int]bool, len(g.names))
+	for i := range sets {
+		sets[i] = make(map[int]bool)
+	}
+	for i, name := range g.names {
+		for _, neighbour := range m.Cities[name].Neighbours {
+			if neighbour == nil {
+				continue
+			}
+			if j, found := index[neighbour.Name]; found && j != i {
+				sets[i][j] = true
+				sets[j][i] = true
+			}
+		}
+	}
+
+	g.adj = make([][]int, len(g.names))
+	for i, set := range sets {
+		for j := range set {
+			g.adj[i] = append(g.adj[i], j)
+		}
+		sort.Ints(g.adj[i])
+	}
+	return g
+}
+
+// distances returns the number of roads from a city to every city it reaches,
+// -1 for the others. The removed city, if any, is considered destroyed.
+func (g mapGraph) distances(from, removed int) []int {
+	dist := make([]int, len(g.names))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[from] = 0
+
+	queue := []int{from}
+	for len(queue) > 0 {
+		city := queue[0]
+		queue = queue[1:]
+		for _, next := range g.adj[city] {
+			if next != removed && dist[next] < 0 {
+				dist[next] = dist[city] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return dist
+}
+
+// pieces returns the groups of connected cities among the given ones,
+// in order of their first city. The removed city, if any, is considered destroyed.
+func (g mapGraph) pieces(cities []int, removed int) [][]int {
+	var groups [][]int
+	seen := make(map[int]bool)
+	for _, city := range cities {
+		if city == removed || seen[city] {
+			continue
+		}
+
+		var group []int
+		for i, d := range g.distances(city, removed) {
+			if d >= 0 {
+				group = append(group, i)
+				seen[i] = true
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// cityNames returns the names of the cities
+func (g mapGraph) cityNames(cities []int) []string {
+	names := make([]string, 0, len(cities))
+	for _, city := range cities {
+		names = append(names, g.names[city])
+	}
+	return names
+}
+
+// AnalyzeMap computes the connectivity of a map: its components, the cities
+// whose destruction splits them and which cities each of them cuts off,
+// the degree distribution, the diameter, and the isolated and dead-end cities.
+// When a city falls, the largest remaining part of its component is kept
+// and the other parts are unreachable.
+func AnalyzeMap(m *Map) MapAnalysis {
+	g := newMapGraph(m)
+	analysis := MapAnalysis{
+		Cities:             len(g.names),
+		Components:         [][]string{},
+		ArticulationPoints: []string{},
+		Degrees:            make(map[int]int),
+		Isolated:           []string{},
+		DeadEnds:           []string{},
+		Falls:              []CityFall{},
+	}
+
+	all := make([]int, len(g.names))
+	for i := range all {
+		all[i] = i
+	}
+	components := g.pieces(all, -1)
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+	for _, component := range components {
+		analysis.Components = append(analysis.Components, g.cityNames(component))
+	}
+
+	for city, neighbours := range g.adj {
+		degree := len(neighbours)
+		analysis.Roads += degree
+		analysis.Degrees[degree]++
+		switch degree {
+		case 0:
+			analysis.Isolated = append(analysis.Isolated, g.names[city])
+		case 1:
+			analysis.DeadEnds = append(analysis.DeadEnds, g.names[city])
+		}
+
+		for _, d := range g.distances(city, -1) {
+			if d > analysis.Diameter {
+				analysis.Diameter = d
+			}
+		}
+
+		// The neighbours of a fallen city are spread over the parts of its component
+		parts := g.pieces(neighbours, city)
+		if len(parts) < 2 {
+			continue
+		}
+		largest := 0
+		for i, part := range parts {
+			if len(part) > len(parts[largest]) {
+				largest = i
+			}
+		}
+
+		var unreachable []int
+		for i, part := range parts {
+			if i != largest {
+				unreachable = append(unreachable, part...)
+			}
+		}
+		sort.Ints(unreachable)
+
+		analysis.ArticulationPoints = append(analysis.ArticulationPoints, g.names[city])
+		analysis.Falls = append(analysis.Falls, CityFall{City: g.names[city], Unreachable: g.cityNames(unreachable)})
+	}
+	analysis.Roads /= 2
+
+	return analysis
+}
+
+// WriteMapAnalysis writes the analysis of a map in the given format: table, json or yaml.
+// An empty format defaults to the table format.
+func WriteMapAnalysis(w io.Writer, analysis MapAnalysis, format string) error {
+	switch format {
+	case "", FormatTable:
+		writeMapAnalysisTable(w, analysis)
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(analysis)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		defer enc.Close()
+		return enc.Encode(analysis)
+	default:
+		return fmt.Errorf("unsupported analysis output format: %s", format)
+	}
+}
+
+// writeMapAnalysisTable prints the analysis of a map in tables
+func writeMapAnalysisTable(w io.Writer, analysis MapAnalysis) {
+	list := func(names []string) string {
+		if len(names) == 0 {
+			return "none"
+		}
+		return strings.Join(names, ", ")
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "+---------------------------- Map Analysis -----------------------------+")
+	fmt.Fprintf(w, "Cities: %d\n", analysis.Cities)
+	fmt.Fprintf(w, "Roads: %d\n", analysis.Roads)
+	fmt.Fprintf(w, "Diameter: %d\n", analysis.Diameter)
+	fmt.Fprintf(w, "Isolated cities: %s\n", list(analysis.Isolated))
+	fmt.Fprintf(w, "Dead-end cities: %s\n", list(analysis.DeadEnds))
+
+	fmt.Fprintf(w, "\nConnected components: %d\n", len(analysis.Components))
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Component", "Size", "Cities"})
+	table.SetAutoWrapText(false)
+	for i, component := range analysis.Components {
+		table.Append([]string{fmt.Sprintf("%d", i+1), fmt.Sprintf("%d", len(component)), strings.Join(component, " ")})
+	}
+	table.Render()
+
+	fmt.Fprintln(w, "\nDegree distribution:")
+	degrees := make([]int, 0, len(analysis.Degrees))
+	for degree := range analysis.Degrees {
+		degrees = append(degrees, degree)
+	}
+	sort.Ints(degrees)
+
+	table = tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Roads", "Cities"})
+	for _, degree := range degrees {
+		table.Append([]string{fmt.Sprintf("%d", degree), fmt.Sprintf("%d", analysis.Degrees[degree])})
+	}
+	table.Render()
+
+	fmt.Fprintf(w, "\nArticulation points: %s\n", list(analysis.ArticulationPoints))
+	if len(analysis.Falls) > 0 {
+		table = tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Falling city", "Unreachable cities"})
+		table.SetAutoWrapText(false)
+		for _, fall := range analysis.Falls {
+			table.Append([]string{fall.City, strings.Join(fall.Unreachable, " ")})
+		}
+		table.Render()
+	}
+	fmt.Fprintln(w, "+-----------------------------------------------------------------------+")
+}
+
+// DefineAnalyzeFlags defines the flags for the analyze command
+func (a *App) DefineAnalyzeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("input", "i", "data/map.txt", "Map input file")
+	cmd.Flags().StringP("format", "f", FormatTable, "Output format of the analysis: table, json or yaml")
+	cmd.Flags().Bool("strict", false, "Validate the map first and refuse it on any error or warning")
+	cmd.Flags().String("directions", DirectionsCompass, "Direction vocabulary of maps without a @directions header: compass, compass8, layers and <direction>=<opposite> pairs")
+}
+
+// StartAnalyze reads a map and prints its analysis, see AnalyzeMap
+func (a *App) StartAnalyze(cmd *cobra.Command, args []string) error {
+	flags := &flagReader{cmd: cmd}
+	inputFilename := flags.String("input")
+	format := flags.String("format")
+	strict := flags.Bool("strict")
+	directions := flags.String("directions")
+	if flags.err != nil {
+		return flags.err
+	}
+	if _, err := ParseDirections(directions); err != nil {
+		return err
+	}
+
+	a.Cfg = &AppCfg{MapInputFile: inputFilename, Format: format, Strict: strict, Directions: directions, Logger: logger.NewDiscardLogger()}
+	if err := a.initLogger(); err != nil {
+		return err
+	}
+	a.initControllers()
+	a.initState()
+
+	if err := a.ioCtrl.ReadMapFromFile(); err != nil {
+		return err
+	}
+
+	return WriteMapAnalysis(cmd.OutOrStdout(), AnalyzeMap(a.State.WorldMap), format)
+}
@@ -1,30 +1,121 @@
 package simulation
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
-	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/derrandz/xtinvasion/pkg/logger"
 
 	"github.com/spf13/cobra"
 )
 
-// AppCfg is the configuration for the app
-type AppCfg struct {
-	MaxMoves      int    // Max number of moves allowed for each alien
-	MapInputFile  string // Map input filepath
-	LogFile       string // Log filepath
-	MapOutputFile string // Map output filepath
-	UseDelay      bool   // Use delay to slow down the simulation for observation
-	DelayMS       int    // Delay in milliseconds to slow down the simulation for observation
+type AppState struct {
+	Aliens            AlienSet
+	AlienLocations    map[*City]AlienSet
+	Defenders         DefenderSet
+	DefenderLocations map[*City]DefenderSet
+	WorldMap          *Map
+	Tick              int            // Number of iterations of the main loop
+	DestroyedCities   []string       // Names of the destroyed cities in order of destruction
+	CityDamage        map[string]int // Number of encounters each city has absorbed
+
+	PopulationLost      int // Population of the destroyed cities
+	DefensesOverwhelmed int // Defense levels of the destroyed cities, see City.DefenseLevel
+
+	FactionLanded map[string]int // Number of aliens landed in each faction, see ParseFactions
+
+	NextAlienID int // ID of the next alien landing or born, IDs are never reused
+	WavesLanded int // Number of reinforcement waves landed, see App.LandWave
+	Births      int // Number of aliens born, see StateController.ReproduceAliens
 }
 
-type AppState struct {
-	Aliens         AlienSet
-	AlienLocations map[*City]AlienSet
-	WorldMap       *Map
+// Clone returns a deep copy of the state sharing no city, alien or map with it,
+// so that it can be read while the simulation keeps running
+func (s *AppState) Clone() AppState {
+	clone := AppState{
+		Aliens:            make(AlienSet, len(s.Aliens)),
+		AlienLocations:    make(map[*City]AlienSet, len(s.AlienLocations)),
+		Defenders:         make(DefenderSet, len(s.Defenders)),
+		DefenderLocations: make(map[*City]DefenderSet, len(s.DefenderLocations)),
+		WorldMap:          &Map{Cities: make(map[string]*City, len(s.WorldMap.Cities)), Directions: s.WorldMap.Directions},
+		Tick:              s.Tick,
+		DestroyedCities:   append([]string(nil), s.DestroyedCities...),
+		CityDamage:        make(map[string]int, len(s.CityDamage)),
+
+		PopulationLost:      s.PopulationLost,
+		DefensesOverwhelmed: s.DefensesOverwhelmed,
+		FactionLanded:       make(map[string]int, len(s.FactionLanded)),
+
+		NextAlienID: s.NextAlienID,
+		WavesLanded: s.WavesLanded,
+		Births:      s.Births,
+	}
+
+	// Cities first, their neighbours and the aliens point to the copies
+	clone.WorldMap.order = append([]string(nil), s.WorldMap.order...)
+	cities := make(map[*City]*City, len(s.WorldMap.Cities))
+	for name, city := range s.WorldMap.Cities {
+		cities[city] = &City{
+			Name:       city.Name,
+			Neighbours: make(map[string]*City, len(city.Neighbours)),
+			Population: city.Population,
+			Defense:    city.Defense,
+			Terrain:    city.Terrain,
+		}
+		clone.WorldMap.Cities[name] = cities[city]
+	}
+	for _, city := range s.WorldMap.Cities {
+		for direction, neighbour := range city.Neighbours {
+			cities[city].Neighbours[direction] = cities[neighbour]
+		}
+		for direction, distance := range city.Distances {
+			cities[city].SetDistance(direction, distance)
+		}
+	}
+
+	for id, alien := range s.Aliens {
+		// strategies are never mutated, they can be shared
+		clone.Aliens[id] = &Alien{ID: alien.ID, CurrentCity: cities[alien.CurrentCity], Moved: alien.Moved, Strategy: alien.Strategy, Arrival: alien.Arrival, Faction: alien.Faction, Reproduced: alien.Reproduced}
+	}
+	for city, aliens := range s.AlienLocations {
+		if cities[city] == nil {
+			// location of a destroyed city
+			continue
+		}
+		location := make(AlienSet, len(aliens))
+		for id := range aliens {
+			location[id] = clone.Aliens[id]
+		}
+		clone.AlienLocations[cities[city]] = location
+	}
+
+	for id, defender := range s.Defenders {
+		clone.Defenders[id] = &Defender{ID: defender.ID, CurrentCity: cities[defender.CurrentCity], Moved: defender.Moved, Arrival: defender.Arrival}
+	}
+	for city, defenders := range s.DefenderLocations {
+		if cities[city] == nil {
+			continue
+		}
+		location := make(DefenderSet, len(defenders))
+		for id := range defenders {
+			location[id] = clone.Defenders[id]
+		}
+		clone.DefenderLocations[cities[city]] = location
+	}
+
+	for name, damage := range s.CityDamage {
+		clone.CityDamage[name] = damage
+	}
+	for faction, landed := range s.FactionLanded {
+		clone.FactionLanded[faction] = landed
+	}
+
+	return clone
 }
 
 // App is the main application
@@ -39,10 +130,31 @@ type App struct {
 	State *AppState // made public for testing
 
 	stateCh chan AppState // used to broadcast state changes to the observers
+	bus     *EventBus     // delivers the events to the subscribers, see Subscribe
+
+	rng    *rand.Rand      // random source of the simulation, seeded from Cfg.Seed
+	rngSrc *countingSource // source of rng, its state is part of the snapshots
+
+	snapshotReqs chan snapshotRequest // snapshots requested while running, see RequestSnapshot
+
+	journal      *Journal // records the state transitions, nil if disabled
+	firstJournal string   // journal file of the first run, the restarted runs journal next to it, see Restart
+
+	reason TerminationReason // why the last run ended, empty if it has not ended
 
 	isStopped int32 // Use int32 for atomic operations
 	ready     chan struct{}
 	done      chan struct{}
+
+	// live controls of the main loop, see control.go
+	running      int32
+	exited       chan struct{} // closed when the last Run has returned
+	paused       int32
+	pendingSteps int32
+	delayMS      int64
+	wake         chan struct{}
+
+	mu sync.Mutex // guards done, running and exited against the control methods
 }
 
 // createAliens creates the aliens and stores them in the app
@@ -51,25 +163,24 @@ func (a *App) createAliens(numAliens int) {
 		alien := &Alien{ID: i, Moved: 0}
 		a.State.Aliens[i] = alien
 	}
+	a.State.NextAlienID = numAliens
 }
 
 // getRandomCity returns a random city from the map
+// cities are sorted by name so that the pick only depends on the random source
 func (a *App) getRandomCity() *City {
-	var cities []*City
-	for _, city := range a.State.WorldMap.Cities {
-		cities = append(cities, city)
-	}
-
-	if len(cities) == 0 {
+	names := a.State.WorldMap.CityNames()
+	if len(names) == 0 {
 		return nil
 	}
 
-	return cities[rand.Intn(len(cities))]
+	return a.State.WorldMap.Cities[names[a.rng.Intn(len(names))]]
 }
 
 // PopulateMapWithAliens assigns aliens to random cities
 func (a *App) PopulateMapWithAliens() {
-	for _, alien := range a.State.Aliens {
+	for _, id := range a.State.Aliens.IDs() {
+		alien := a.State.Aliens[id]
 		city := a.getRandomCity()
 		if location, found := a.State.AlienLocations[city]; found {
 			location[alien.ID] = alien
@@ -77,6 +188,8 @@ func (a *App) PopulateMapWithAliens() {
 			a.State.AlienLocations[city] = map[int]*Alien{alien.ID: alien}
 		}
 		alien.CurrentCity = city
+		a.stateCtrl.countLanding(alien)
+		a.stateCtrl.emit(Event{Type: EventAlienLanded, Alien: alien.ID, City: city.Name, Faction: alien.Faction})
 	}
 }
 
@@ -89,146 +202,280 @@ func (a *App) DefineFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("log", "o", "output/stdout.log", "Log file")
 	cmd.Flags().BoolP("delay", "d", false, "Use delay to slow down the simulation for observation")
 	cmd.Flags().IntP("delay_ms", "s", 1000, "Delay in milliseconds to slow down the simulation for observation")
+	cmd.Flags().Int64("seed", 0, "Seed of the random source, use it to replay a simulation (0 picks a random seed)")
+	cmd.Flags().StringP("journal", "j", "", "Journal file recording every state transition (disabled if empty)")
+	cmd.Flags().StringP("format", "f", FormatTable, "Output format of the result: table, json, yaml or csv")
+	cmd.Flags().Bool("strict", false, "Validate the map first and refuse it on any error or warning")
+	cmd.Flags().String("directions", DirectionsCompass, "Direction vocabulary of maps without a @directions header: compass, compass8, layers and <direction>=<opposite> pairs")
+	cmd.Flags().String("strategy", "uniform", "Movement strategy of the aliens: uniform, weighted:<direction>=<weight>,..., seek, avoid or lazy:<stay chance>[:<strategy>]")
+	cmd.Flags().StringArray("alien_strategy", nil, "Movement strategy of a single alien as <alien id>=<strategy>, can be repeated")
+	cmd.Flags().String("factions", "", "Factions of the aliens as <name>:<share>,... where the shares are counts or ratios (no faction if empty)")
+	cmd.Flags().Int("waves", 0, "Number of reinforcement waves landing after the first aliens")
+	cmd.Flags().Int("wave_size", 5, "Number of aliens landing in each reinforcement wave")
+	cmd.Flags().Int("wave_every", 10, "Number of ticks between two reinforcement waves")
+	cmd.Flags().StringArray("wave_city", nil, "City the reinforcements land in, in turn, can be repeated (random cities if none)")
+	cmd.Flags().Int("reproduce_after", 0, "Number of moves after which an alien gives birth to an offspring (no reproduction if 0)")
+	cmd.Flags().Int("defenders", 0, "Number of defenders")
+	cmd.Flags().StringArray("defender_city", nil, "City the defenders land in, in turn, can be repeated (random cities if none)")
+	cmd.Flags().String("defender_strategy", "uniform", "Movement strategy of the defenders, see --strategy: seek rushes to the nearest aliens, avoid keeps to the quietest cities")
+	cmd.Flags().Int("threshold", 2, "Number of aliens in a city triggering an encounter")
+	cmd.Flags().Float64("survival_chance", 0, "Chance of each alien surviving an encounter in destroy mode")
+	cmd.Flags().String("collision_mode", string(CollisionDestroy), "Outcome of an encounter: destroy (every alien dies) or fight (a single alien survives)")
+	cmd.Flags().Int("city_hp", 1, "Number of encounters a city absorbs before falling")
+	cmd.Flags().String("tick_mode", string(TickSequential), "How aliens move during a tick: sequential (one after the other) or simultaneous (all at once)")
+	cmd.Flags().Bool("road_encounters", false, "Make aliens crossing on a road fight, requires the simultaneous tick mode")
+	cmd.Flags().String("resume", "", "Snapshot file to resume the simulation from, the map and aliens are taken from it")
+	cmd.Flags().String("snapshot", "", "Snapshot file written when the simulation ends or is interrupted (disabled if empty)")
 }
 
-// parseFlags parses the flags for the app
-func (a *App) parseFlags(cmd *cobra.Command) []any {
-	numAliens, _ := cmd.Flags().GetInt("aliens")
-	maxMoves, _ := cmd.Flags().GetInt("max_moves")
-	inputFilename, _ := cmd.Flags().GetString("input")
-	outputFilename, _ := cmd.Flags().GetString("output")
-	logfile, _ := cmd.Flags().GetString("log")
-	useDelay, _ := cmd.Flags().GetBool("delay")
-	delayMS, _ := cmd.Flags().GetInt("delay_ms")
+// DefineReplayFlags defines the flags for the replay command
+func (a *App) DefineReplayFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("journal", "j", "output/journal.jsonl", "Journal file to replay")
+	cmd.Flags().StringP("input", "i", "", "Map input file (defaults to the map recorded in the journal)")
+	cmd.Flags().StringP("output", "l", "output/replay_map.txt", "Map output file")
+	cmd.Flags().StringP("log", "o", "output/replay.log", "Log file")
+	cmd.Flags().IntP("until", "u", -1, "Stop the replay after the given tick (-1 replays the whole journal)")
+	cmd.Flags().BoolP("verbose", "v", false, "Print every replayed event")
+	cmd.Flags().StringP("format", "f", FormatTable, "Output format of the result: table, json, yaml or csv")
+}
 
-	return []any{
-		numAliens,
-		maxMoves,
-		inputFilename,
-		outputFilename,
-		logfile,
-		useDelay,
-		delayMS,
+// Init initializes the app from the command flags
+// See InitWithConfig
+func (a *App) Init(cmd *cobra.Command) error {
+	cfg, err := ConfigFromFlags(cmd)
+	if err != nil {
+		return err
 	}
+
+	return a.InitWithConfig(cfg)
 }
 
-// Init initializes the app by reading the input file and creating the cities
+// InitWithConfig initializes the app by reading the input file and creating the cities
 // as well populating them with aliens
 // other necessary state, logger and controllers initialization is done here
-func (a *App) Init(cmd *cobra.Command) {
+func (a *App) InitWithConfig(cfg AppCfg) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
 	a.done = make(chan struct{})
 	a.isStopped = 0
-
-	// Read the map from the file and create the cities
-	flags := a.parseFlags(cmd)
+	a.reason = ""
+	a.pendingSteps = 0
 
 	// store configuration
-	a.Cfg = &AppCfg{
-		MaxMoves:      flags[1].(int),
-		MapInputFile:  flags[2].(string),
-		MapOutputFile: flags[3].(string),
-		LogFile:       flags[4].(string),
-		UseDelay:      flags[5].(bool),
-		DelayMS:       flags[6].(int),
-	}
-
-	// Initialize the logger
-	if a.Cfg.LogFile == "" {
-		a.logger = logger.NewStdoutLogger()
+	a.Cfg = &cfg
+	if a.Cfg.UseDelay {
+		a.SetDelay(a.Cfg.DelayMS)
 	} else {
-		if loggr, err := logger.NewFileLogger(a.Cfg.LogFile); err != nil {
-			fmt.Printf("error creating logger: %v", err)
-			panic(err)
-		} else {
-			a.logger = loggr
-		}
+		a.SetDelay(0)
 	}
 
-	// Initialize the state and io controllers
-	a.stateCtrl = &StateController{app: a}
-	a.ioCtrl = &IOController{app: a}
+	// Seed the random source, the seed is kept in the config to be reported
+	if a.Cfg.Seed == 0 {
+		a.Cfg.Seed = time.Now().UnixNano()
+	}
+	a.SetSeed(a.Cfg.Seed)
 
-	// Initialize the map and aliens (state)
-	numAliens := flags[0].(int)
+	// Initialize the logger, the controllers and an empty state
+	if err := a.initLogger(); err != nil {
+		return err
+	}
+	a.initControllers()
+	a.initState()
+
+	// Resume a snapshot instead of starting afresh
+	if a.Cfg.ResumeFile != "" {
+		if err := a.RestoreFromFile(a.Cfg.ResumeFile); err != nil {
+			a.logger.Logf("error: %v", err)
+			return err
+		}
 
-	a.State = &AppState{
-		Aliens:         make(AlienSet, numAliens),
-		AlienLocations: make(map[*City]AlienSet),
-		WorldMap:       &Map{Cities: make(map[string]*City)},
+		vocabulary := a.State.WorldMap.Vocabulary()
+		strategy, _ := ParseStrategy(a.Cfg.Strategy)
+		a.stateCtrl.SetStrategy(withVocabulary(strategy, vocabulary))
+		defenderStrategy, _ := ParseStrategy(a.Cfg.DefenderStrategy)
+		a.stateCtrl.SetDefenderStrategy(withVocabulary(defenderStrategy, vocabulary))
+		a.markReady()
+		return nil
 	}
 
 	// Read the map from the file and create the cities
 	if err := a.ioCtrl.ReadMapFromFile(); err != nil {
 		a.logger.Logf("error: %v", err)
-		panic(err)
+		return err
+	}
+
+	if len(a.State.WorldMap.Cities) == 0 && a.Cfg.NumAliens+a.Cfg.NumDefenders > 0 {
+		err := fmt.Errorf("map %s has no city for the aliens to land in", a.Cfg.MapInputFile)
+		a.logger.Logf("error: %v", err)
+		return err
+	}
+
+	// Open the journal and record the simulation parameters
+	a.journal = nil
+	if a.Cfg.JournalFile != "" {
+		journal, err := CreateJournal(a.Cfg.JournalFile)
+		if err != nil {
+			a.logger.Logf("error: %v", err)
+			return err
+		}
+		a.journal = journal
+		a.stateCtrl.emit(Event{Type: EventSimulationStarted, Map: a.Cfg.MapInputFile, Seed: a.Cfg.Seed, Directions: a.Cfg.Directions, MaxMoves: a.Cfg.MaxMoves})
 	}
 
-	// Create aliens and assign them to cities
-	a.createAliens(numAliens)
+	for _, name := range a.Cfg.WaveCities {
+		if _, found := a.State.WorldMap.Cities[name]; !found {
+			err := fmt.Errorf("city %s of the reinforcements does not exist in world map", name)
+			a.logger.Logf("error: %v", err)
+			return err
+		}
+	}
+
+	// Create aliens and assign them to factions and cities
+	a.createAliens(a.Cfg.NumAliens)
+	a.assignFactions()
+
+	// Set the movement strategies, the config has been validated
+	vocabulary := a.State.WorldMap.Vocabulary()
+	strategy, _ := ParseStrategy(a.Cfg.Strategy)
+	a.stateCtrl.SetStrategy(withVocabulary(strategy, vocabulary))
+	alienStrategies, _ := ParseAlienStrategies(a.Cfg.AlienStrategies)
+	for id, strategy := range alienStrategies {
+		if alien, found := a.State.Aliens[id]; found {
+			alien.Strategy = withVocabulary(strategy, vocabulary)
+		}
+	}
 
 	// Populate the alien locations
 	a.PopulateMapWithAliens()
 
-	close(a.ready)
+	// Land the defenders once the aliens have landed, in the configured cities if any
+	a.createDefenders(a.Cfg.NumDefenders)
+	defenderStrategy, _ := ParseStrategy(a.Cfg.DefenderStrategy)
+	a.stateCtrl.SetDefenderStrategy(withVocabulary(defenderStrategy, vocabulary))
+	if err := a.PopulateMapWithDefenders(); err != nil {
+		a.logger.Logf("error: %v", err)
+		return err
+	}
+
+	a.markReady()
+	return nil
 }
 
-// Run runs the main loop of the app
-func (a *App) Run() {
-	for {
-		// Check if the app has been stopped
-		if atomic.LoadInt32(&a.isStopped) == 1 {
-			break
-		}
+// markReady closes the ready channel.
+// The app may be initialized more than once, ready stays closed
+func (a *App) markReady() {
+	select {
+	case <-a.ready:
+	default:
+		close(a.ready)
+	}
+}
 
-		if a.Cfg.UseDelay {
-			// Sleep for a while to slow down the simulation for observation
-			a.logger.Logf("sleeping for %d ms", a.Cfg.DelayMS)
-			sleepMS(a.Cfg.DelayMS)
-		}
+// initLogger initializes the logger, logging to stdout if no log file is configured
+func (a *App) initLogger() error {
+	if a.Cfg.Logger != nil {
+		a.logger = a.Cfg.Logger
+		return nil
+	}
 
-		// Check if all aliens have been destroyed
-		if a.stateCtrl.AreAllAliensDestroyed() {
-			a.logger.Log("All aliens have been destroyed.")
-			break
+	if a.Cfg.LogFile == "" {
+		a.logger = logger.NewStdoutLogger()
+		return nil
+	}
+
+	loggr, err := logger.NewFileLogger(a.Cfg.LogFile)
+	if err != nil {
+		return fmt.Errorf("error creating logger: %w", err)
+	}
+	a.logger = loggr
+
+	return nil
+}
+
+// initControllers initializes the state and io controllers
+func (a *App) initControllers() {
+	a.stateCtrl = &StateController{app: a}
+	a.ioCtrl = &IOController{app: a}
+}
+
+// initState initializes an empty state, the map and aliens are loaded afterwards
+func (a *App) initState() {
+	a.State = &AppState{
+		Aliens:            make(AlienSet, a.Cfg.NumAliens),
+		AlienLocations:    make(map[*City]AlienSet),
+		Defenders:         make(DefenderSet, a.Cfg.NumDefenders),
+		DefenderLocations: make(map[*City]DefenderSet),
+		WorldMap:          &Map{Cities: make(map[string]*City)},
+		CityDamage:        make(map[string]int),
+	}
+}
+
+// Run runs the main loop of the app until the simulation terminates,
+// the app is stopped or the context is done. Each iteration is a Step.
+// The returned error is the context error if the run was cancelled or timed out.
+func (a *App) Run(ctx context.Context) (Result, error) {
+	var err error
+
+	a.mu.Lock()
+	atomic.StoreInt32(&a.running, 1)
+	exited := make(chan struct{})
+	a.exited = exited
+	a.mu.Unlock()
+	defer func() {
+		atomic.StoreInt32(&a.running, 0)
+		close(exited)
+	}()
+
+	for a.reason == "" {
+		// Serve the snapshot requested since the last tick
+		select {
+		case req := <-a.snapshotReqs:
+			req.err <- a.Snapshot(req.w)
+		default:
 		}
 
-		// Check if all aliens have moved 10,000 times
-		if a.stateCtrl.IsAlienMovementLimitReached() {
-			a.logger.Log("All aliens have moved 10,000 times.")
+		// Check if the app has been stopped
+		if atomic.LoadInt32(&a.isStopped) == 1 {
+			a.end(ReasonStopped)
 			break
 		}
 
-		if a.stateCtrl.AreRemainingAliensTrapped() {
-			a.logger.Log("All remaining aliens are trapped.")
+		// Check if the run has been cancelled or timed out
+		if err = ctx.Err(); err != nil {
+			a.end(contextReason(err))
 			break
 		}
 
-		// Check if any city has two or more aliens and destroy them
-		for city := range a.State.AlienLocations {
-			if len(a.State.AlienLocations[city]) > 1 {
-				err := a.stateCtrl.DestroyCity(city.Name)
-				if err != nil {
-					a.logger.Logf("error: %v", err)
+		// Wait while paused, unless a single tick is requested
+		stepping := false
+		if a.IsPaused() {
+			if stepping = a.takeStepRequest(); !stepping {
+				if err = a.waitControl(ctx); err != nil {
+					a.end(contextReason(err))
+					break
 				}
+				continue
 			}
 		}
 
-		// Move aliens around in the map
-		for _, alien := range a.State.Aliens {
-			if alien != nil {
-				err := a.stateCtrl.MoveAlienToNextCity(alien)
-				if err != nil && !strings.Contains(err.Error(), "getRandomNeighbor: city has no neighbours") {
-					a.logger.Logf("error: %v", err)
-				}
+		if delay := a.Delay(); delay > 0 && !stepping {
+			// Sleep for a while to slow down the simulation for observation
+			a.logger.Logf("sleeping for %d ms", delay)
+			if err = sleepContext(ctx, delay); err != nil {
+				a.end(contextReason(err))
+				break
 			}
 		}
 
-		// Broadcast state changes to the observers
-		a.stateCtrl.BroadcastStateChanges()
+		// Execute a tick, or end the simulation if it is over
+		if _, err := a.Step(); err != nil {
+			a.logger.Logf("error: %v", err)
+		}
 	}
 
-	// Indicate that the main loop has finished by closing the channel
-	close(a.done)
+	return a.Result(), err
 }
 
 // Ready returns a channel that is closed when the app is ready
@@ -236,23 +483,39 @@ func (a *App) Ready() <-chan struct{} {
 	return a.ready
 }
 
+// Subscribe subscribes to the events of the simulation, see EventBus.Subscribe.
+// The subscription outlives the runs and reinitializations of the app.
+func (a *App) Subscribe(opts SubscribeOptions) (<-chan Event, func(), error) {
+	return a.bus.Subscribe(opts)
+}
+
+// Done returns a channel that is closed when the main loop has finished
+func (a *App) Done() <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.done
+}
+
 // Stop stops the main loop of app
 func (a *App) Stop() {
 	atomic.StoreInt32(&a.isStopped, 1)
+	a.wakeUp()
 }
 
 // Wait waits for the main loop to finish
 func (a *App) Wait() {
 	// Wait for the main loop to finish by waiting for the loopDone channel to be closed
-	<-a.done
+	<-a.Done()
 }
 
 // SaveResult saves the result of the simulation
 // in the form of an output file of the remaining cities (similar to the input file)
 // as well as it prints the result to stdout
-func (a *App) SaveResult() {
-	a.ioCtrl.WriteMapToFile()
-	a.ioCtrl.PrintResult()
+func (a *App) SaveResult() error {
+	if err := a.ioCtrl.WriteMapToFile(); err != nil {
+		return fmt.Errorf("error writing map: %w", err)
+	}
+	return a.ioCtrl.PrintResult()
 }
 
 // IsStopped returns true if the app has been stopped
@@ -262,10 +525,95 @@ func (a *App) IsStopped() bool {
 
 // Start starts the app by initializing it and running it
 // as well as saving the result after the main loop has finished
-func (a *App) Start(cmd *cobra.Command, args []string) {
-	a.Init(cmd)
-	a.Run()
-	a.SaveResult()
+func (a *App) Start(cmd *cobra.Command, args []string) error {
+	if err := a.Init(cmd); err != nil {
+		return err
+	}
+
+	// a cancelled run still has a result worth saving, and resuming
+	_, runErr := a.Run(cmd.Context())
+	if a.Cfg.SnapshotFile != "" {
+		if err := a.SnapshotToFile(a.Cfg.SnapshotFile); err != nil {
+			return err
+		}
+	}
+	if err := a.SaveResult(); err != nil {
+		return err
+	}
+
+	// an interrupt is a normal ending, its result is saved above
+	if errors.Is(runErr, context.Canceled) {
+		return nil
+	}
+	return runErr
+}
+
+// StartReplay rebuilds the state of a simulation from its journal
+// and saves the result of the replayed simulation
+func (a *App) StartReplay(cmd *cobra.Command, args []string) error {
+	flags := &flagReader{cmd: cmd}
+	journalFile := flags.String("journal")
+	inputFilename := flags.String("input")
+	outputFilename := flags.String("output")
+	logfile := flags.String("log")
+	until := flags.Int("until")
+	verbose := flags.Bool("verbose")
+	format := flags.String("format")
+	if flags.err != nil {
+		return flags.err
+	}
+
+	// Refuse an unknown format before replaying and writing the map output
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+
+	events, err := ReadJournalFile(journalFile)
+	if err != nil {
+		return err
+	}
+
+	a.Cfg = &AppCfg{MapInputFile: inputFilename, MapOutputFile: outputFilename, LogFile: logfile, Format: format}
+	for _, e := range events {
+		if e.Type == EventSimulationStarted {
+			if a.Cfg.MapInputFile == "" {
+				a.Cfg.MapInputFile = e.Map
+			}
+			a.Cfg.Seed = e.Seed
+			a.Cfg.Directions = e.Directions
+			a.Cfg.MaxMoves = e.MaxMoves
+		}
+	}
+
+	if err := a.initLogger(); err != nil {
+		return err
+	}
+	a.initControllers()
+	a.initState()
+
+	if err := a.ioCtrl.ReadMapFromFile(); err != nil {
+		return err
+	}
+
+	if verbose {
+		for _, e := range events {
+			if until >= 0 && e.Tick > until {
+				break
+			}
+			fmt.Println(e)
+		}
+	}
+
+	if _, err := a.Replay(events, until); err != nil {
+		return err
+	}
+
+	// The journal stopped before the end of the simulation
+	if a.reason == "" {
+		a.reason = ReasonInProgress
+	}
+
+	return a.SaveResult()
 }
 
 // Setter for testing
@@ -288,6 +636,22 @@ func (a *App) SetIOController(ioCtrl *IOController) {
 	a.ioCtrl = ioCtrl
 }
 
+// SetSeed reseeds the random source of the app
+func (a *App) SetSeed(seed int64) {
+	a.rngSrc = newCountingSource(seed)
+	a.rng = rand.New(a.rngSrc)
+}
+
+// Rand returns the random source of the app
+func (a *App) Rand() *rand.Rand {
+	return a.rng
+}
+
+// SetJournal sets the journal recording the state transitions
+func (a *App) SetJournal(journal *Journal) {
+	a.journal = journal
+}
+
 // SetLogger Sets the logger
 func (a *App) SetLogger(logger *logger.Logger) {
 	a.logger = logger
@@ -302,7 +666,22 @@ func NewApp() *App {
 		done:      make(chan struct{}),
 		isStopped: 0,
 		stateCh:   make(chan AppState),
+		bus:       NewEventBus(),
 		Cfg:       &AppCfg{},
+
+		snapshotReqs: make(chan snapshotRequest),
+		wake:         make(chan struct{}, 1),
 	}
+	app.SetSeed(time.Now().UnixNano())
 	return app
 }
+
+// NewAppFromConfig creates a new app and initializes it from the given configuration.
+// It allows embedding the simulation without going through the command line.
+func NewAppFromConfig(cfg AppCfg) (*App, error) {
+	app := NewApp()
+	if err := app.InitWithConfig(cfg); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
@@ -0,0 +1,126 @@
+package simulation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AttributePrefix starts the city attributes of a map line, written after the roads
+// as @<key>=<value>, see City.SetAttribute
+const AttributePrefix = "@"
+
+// City attributes of the map file
+const (
+	AttributePopulation = "population" // inhabitants of the city
+	AttributeDefense    = "defense"    // encounters the city absorbs on top of the collision rules
+	AttributeTerrain    = "terrain"    // terrain of the city, see Terrain
+)
+
+// Terrain is the terrain of a city, it slows down the aliens heading to the city
+// and may add to its defense
+type Terrain string
+
+const (
+	TerrainPlains    Terrain = "plains"
+	TerrainForest    Terrain = "forest"
+	TerrainSwamp     Terrain = "swamp"
+	TerrainHills     Terrain = "hills"
+	TerrainMountains Terrain = "mountains"
+)
+
+// terrainTraits are the effects of a terrain on the simulation
+type terrainTraits struct {
+	passability float64 // chance of an alien entering the city once it decided to
+	defense     int     // defense bonus of the city
+}
+
+var terrains = map[Terrain]terrainTraits{
+	TerrainPlains:    {passability: 1},
+	TerrainForest:    {passability: 0.75},
+	TerrainSwamp:     {passability: 0.5},
+	TerrainHills:     {passability: 0.5, defense: 1},
+	TerrainMountains: {passability: 0.25, defense: 2},
+}
+
+// ParseTerrain parses a terrain, an empty terrain is plains
+func ParseTerrain(terrain string) (Terrain, error) {
+	if terrain == "" {
+		return TerrainPlains, nil
+	}
+	if _, found := terrains[Terrain(terrain)]; !found {
+		return "", fmt.Errorf("unknown terrain %q", terrain)
+	}
+	return Terrain(terrain), nil
+}
+
+// Passability returns the chance of an alien entering a city of this terrain
+// once its strategy chose to, see StateController.NextCity
+func (t Terrain) Passability() float64 {
+	if traits, found := terrains[t]; found {
+		return traits.passability
+	}
+	return 1
+}
+
+// Defense returns the defense bonus of a city of this terrain
+func (t Terrain) Defense() int {
+	return terrains[t].defense
+}
+
+// SetAttribute sets a city attribute from its map file value
+func (c *City) SetAttribute(key, value string) error {
+	switch key {
+	case AttributePopulation, AttributeDefense:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q of %s, expected a non-negative number", key, value, c.Name)
+		}
+		if key == AttributePopulation {
+			c.Population = n
+		} else {
+			c.Defense = n
+		}
+	case AttributeTerrain:
+		terrain, err := ParseTerrain(value)
+		if err != nil {
+			return fmt.Errorf("invalid terrain of %s: %w", c.Name, err)
+		}
+		c.Terrain = terrain
+	default:
+		return fmt.Errorf("unknown city attribute %q", key)
+	}
+	return nil
+}
+
+// attributeTokens returns the attributes of the city set to other values
+// than the defaults, in the map file format
+func (c *City) attributeTokens() []string {
+	var tokens []string
+	if c.Population > 0 {
+		tokens = append(tokens, fmt.Sprintf("%s%s=%d", AttributePrefix, AttributePopulation, c.Population))
+	}
+	if c.Defense > 0 {
+		tokens = append(tokens, fmt.Sprintf("%s%s=%d", AttributePrefix, AttributeDefense, c.Defense))
+	}
+	if c.Terrain != "" && c.Terrain != TerrainPlains {
+		tokens = append(tokens, fmt.Sprintf("%s%s=%s", AttributePrefix, AttributeTerrain, c.Terrain))
+	}
+	return tokens
+}
+
+// DefenseLevel returns the number of encounters the city absorbs
+// on top of the collision rules: its defense plus the bonus of its terrain
+func (c *City) DefenseLevel() int {
+	return c.Defense + c.Terrain.Defense()
+}
+
+// parseAttribute parses a city attribute token of a map line,
+// found is false if the token is not an attribute
+func parseAttribute(text string) (key, value string, found bool) {
+	if !strings.HasPrefix(text, AttributePrefix) {
+		return "", "", false
+	}
+	key, value, _ = strings.Cut(strings.TrimPrefix(text, AttributePrefix), "=")
+	return key, value, true
+}
@@ -0,0 +1,342 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// BatchCfg is the configuration of a batch of simulations
+type BatchCfg struct {
+	Sim     AppCfg // Configuration shared by all the simulations
+	Runs    int    // Number of simulations to run
+	Workers int    // Number of simulations running concurrently, defaults to the number of CPUs
+}
+
+// Distribution summarizes a set of samples
+type Distribution struct {
+	Mean   float64 `json:"mean" yaml:"mean"`
+	StdDev float64 `json:"stddev" yaml:"stddev"`
+	Min    float64 `json:"min" yaml:"min"`
+	Median float64 `json:"median" yaml:"median"`
+	Max    float64 `json:"max" yaml:"max"`
+}
+
+// CityDestruction is the probability of a city being destroyed during a simulation
+type CityDestruction struct {
+	City        string  `json:"city" yaml:"city"`
+	Probability float64 `json:"probability" yaml:"probability"`
+}
+
+// BatchReport aggregates the results of a batch of simulations
+type BatchReport struct {
+	Runs            int                       `json:"runs" yaml:"runs"`
+	FirstSeed       int64                     `json:"first_seed" yaml:"first_seed"` // run i is seeded with FirstSeed + i
+	Cities          int                       `json:"cities" yaml:"cities"`
+	SurvivingCities Distribution              `json:"surviving_cities_pct" yaml:"surviving_cities_pct"` // percentage of cities surviving a run
+	Ticks           Distribution              `json:"ticks" yaml:"ticks"`
+	CityDestruction []CityDestruction         `json:"city_destruction" yaml:"city_destruction"` // sorted by decreasing probability
+	Reasons         map[TerminationReason]int `json:"reasons" yaml:"reasons"`
+}
+
+// RunBatch runs independent simulations concurrently and aggregates their results.
+// Each simulation is seeded with the seed of the configuration plus its index,
+// a zero seed picks a time based first seed.
+func RunBatch(ctx context.Context, cfg BatchCfg) (BatchReport, error) {
+	if cfg.Runs <= 0 {
+		return BatchReport{}, fmt.Errorf("invalid batch config: number of runs must be positive, got %d", cfg.Runs)
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.Sim.Seed == 0 {
+		cfg.Sim.Seed = time.Now().UnixNano()
+	}
+	if err := cfg.Sim.Validate(); err != nil {
+		return BatchReport{}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results = make([]Result, cfg.Runs)
+		jobs    = make(chan int)
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		runErr  error
+	)
+
+	fail := func(err error) {
+		errOnce.Do(func() {
+			runErr = err
+			cancel()
+		})
+	}
+
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res, err := runBatchSimulation(ctx, cfg.Sim, i)
+				if err != nil {
+					fail(fmt.Errorf("run %d: %w", i, err))
+					continue
+				}
+				results[i] = res
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < cfg.Runs; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if runErr != nil {
+		return BatchReport{}, runErr
+	}
+	if err := ctx.Err(); err != nil {
+		return BatchReport{}, err
+	}
+
+	return aggregateResults(results, cfg.Sim.Seed), nil
+}
+
+// runBatchSimulation runs the i-th simulation of a batch
+// without delay, journal, log or output file
+func runBatchSimulation(ctx context.Context, base AppCfg, i int) (Result, error) {
+	cfg := base
+	cfg.Seed = base.Seed + int64(i)
+	cfg.UseDelay = false
+	cfg.JournalFile = ""
+	cfg.MapOutputFile = ""
+	cfg.Logger = logger.NewDiscardLogger()
+
+	app, err := NewAppFromConfig(cfg)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return app.Run(ctx)
+}
+
+// aggregateResults computes the statistics of a batch of results
+func aggregateResults(results []Result, firstSeed int64) BatchReport {
+	report := BatchReport{
+		Runs:      len(results),
+		FirstSeed: firstSeed,
+		Reasons:   make(map[TerminationReason]int),
+	}
+
+	destroyed := make(map[string]int)
+	surviving := make([]float64, 0, len(results))
+	ticks := make([]float64, 0, len(results))
+
+	for _, res := range results {
+		cities := len(res.RemainingCities) + len(res.DestroyedCities)
+		if cities > report.Cities {
+			report.Cities = cities
+		}
+
+		for _, city := range res.RemainingCities {
+			if _, found := destroyed[city.Name]; !found {
+				destroyed[city.Name] = 0
+			}
+		}
+		for _, city := range res.DestroyedCities {
+			destroyed[city]++
+		}
+
+		if cities > 0 {
+			surviving = append(surviving, 100*float64(len(res.RemainingCities))/float64(cities))
+		}
+		ticks = append(ticks, float64(res.Ticks))
+		report.Reasons[res.Reason]++
+	}
+
+	report.SurvivingCities = newDistribution(surviving)
+	report.Ticks = newDistribution(ticks)
+
+	for city, count := range destroyed {
+		report.CityDestruction = append(report.CityDestruction, CityDestruction{
+			City:        city,
+			Probability: float64(count) / float64(len(results)),
+		})
+	}
+	sort.Slice(report.CityDestruction, func(i, j int) bool {
+		a, b := report.CityDestruction[i], report.CityDestruction[j]
+		if a.Probability != b.Probability {
+			return a.Probability > b.Probability
+		}
+		return a.City < b.City
+	})
+
+	return report
+}
+
+// newDistribution summarizes the samples
+func newDistribution(samples []float64) Distribution {
+	if len(samples) == 0 {
+		return Distribution{}
+	}
+
+	sorted := append([]float64{}, samples...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, s := range sorted {
+		sum += s
+	}
+	mean := sum / float64(len(sorted))
+
+	variance := 0.0
+	for _, s := range sorted {
+		variance += (s - mean) * (s - mean)
+	}
+	variance /= float64(len(sorted))
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	return Distribution{
+		Mean:   mean,
+		StdDev: math.Sqrt(variance),
+		Min:    sorted[0],
+		Median: median,
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// WriteBatchReport writes the batch report in the given format: table, json or yaml.
+// An empty format defaults to the table format.
+func WriteBatchReport(w io.Writer, report BatchReport, format string) error {
+	switch format {
+	case "", FormatTable:
+		writeBatchReportTable(w, report)
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		defer enc.Close()
+		return enc.Encode(report)
+	default:
+		return fmt.Errorf("unsupported batch output format: %s", format)
+	}
+}
+
+// writeBatchReportTable prints the batch report in tables
+func writeBatchReportTable(w io.Writer, report BatchReport) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "+--------------------------- Batch Result ------------------------------+")
+	fmt.Fprintf(w, "Runs: %d (seeds %d to %d)\n", report.Runs, report.FirstSeed, report.FirstSeed+int64(report.Runs)-1)
+	fmt.Fprintf(w, "Cities: %d\n\n", report.Cities)
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Metric", "Mean", "Std Dev", "Min", "Median", "Max"})
+	for _, row := range []struct {
+		name string
+		dist Distribution
+	}{
+		{"Surviving cities (%)", report.SurvivingCities},
+		{"Ticks", report.Ticks},
+	} {
+		table.Append([]string{
+			row.name,
+			fmt.Sprintf("%.2f", row.dist.Mean),
+			fmt.Sprintf("%.2f", row.dist.StdDev),
+			fmt.Sprintf("%.2f", row.dist.Min),
+			fmt.Sprintf("%.2f", row.dist.Median),
+			fmt.Sprintf("%.2f", row.dist.Max),
+		})
+	}
+	table.Render()
+
+	fmt.Fprintln(w, "\nCity destruction probability:")
+	table = tablewriter.NewWriter(w)
+	table.SetHeader([]string{"City", "Probability"})
+	for _, city := range report.CityDestruction {
+		table.Append([]string{city.City, fmt.Sprintf("%.2f%%", 100*city.Probability)})
+	}
+	table.Render()
+
+	fmt.Fprintln(w, "\nTermination reasons:")
+	reasons := make([]string, 0, len(report.Reasons))
+	for reason := range report.Reasons {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+
+	table = tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Reason", "Runs", "Share"})
+	for _, reason := range reasons {
+		count := report.Reasons[TerminationReason(reason)]
+		table.Append([]string{reason, fmt.Sprintf("%d", count), fmt.Sprintf("%.2f%%", 100*float64(count)/float64(report.Runs))})
+	}
+	table.Render()
+	fmt.Fprintln(w, "+-----------------------------------------------------------------------+")
+}
+
+// DefineBatchFlags defines the flags for the batch command,
+// every simulation flag of the start command is accepted, see StartBatch for the flags rejected
+func (a *App) DefineBatchFlags(cmd *cobra.Command) {
+	a.DefineFlags(cmd)
+	cmd.Flags().IntP("runs", "n", 100, "Number of simulations to run")
+	cmd.Flags().IntP("workers", "w", runtime.NumCPU(), "Number of simulations running concurrently")
+}
+
+// StartBatch runs a batch of simulations and prints the aggregated report
+func (a *App) StartBatch(cmd *cobra.Command, args []string) error {
+	cfg, err := ConfigFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.Format == FormatCSV {
+		return fmt.Errorf("unsupported batch output format: %s", cfg.Format)
+	}
+	if cfg.ResumeFile != "" || cfg.SnapshotFile != "" {
+		return fmt.Errorf("a batch of simulations cannot be snapshotted or resumed")
+	}
+	// The runs of a batch neither write files nor get observed
+	for _, name := range []string{"journal", "delay", "delay_ms", "log", "output"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("a batch of simulations does not support the --%s flag", name)
+		}
+	}
+
+	flags := &flagReader{cmd: cmd}
+	batchCfg := BatchCfg{Sim: cfg, Runs: flags.Int("runs"), Workers: flags.Int("workers")}
+	if flags.err != nil {
+		return flags.err
+	}
+
+	report, err := RunBatch(cmd.Context(), batchCfg)
+	if err != nil {
+		return err
+	}
+
+	return WriteBatchReport(cmd.OutOrStdout(), report, cfg.Format)
+}
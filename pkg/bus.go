@@ -0,0 +1,228 @@
+package simulation
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DropPolicy decides what happens to the events of a subscriber not keeping up with the simulation
+type DropPolicy string
+
+const (
+	// PolicyLatestOnly keeps only the latest undelivered event
+	PolicyLatestOnly DropPolicy = "latest"
+	// PolicyBlock never drops an event, the simulation waits for the subscriber
+	PolicyBlock DropPolicy = "block"
+	// PolicyRingBuffer keeps the last undelivered events, dropping the oldest ones
+	PolicyRingBuffer DropPolicy = "ring"
+)
+
+// defaultBufferSize is the size of the ring buffer of a subscriber when none is given
+const defaultBufferSize = 256
+
+// SubscribeOptions are the options of a subscription to the events of a simulation
+type SubscribeOptions struct {
+	Policy DropPolicy  // ring buffer if empty
+	Buffer int         // size of the ring buffer, or of the channel buffer with PolicyBlock
+	Types  []EventType // types of the events to receive, every type if empty
+}
+
+// Validate returns an error if the options cannot be used to subscribe
+func (opts SubscribeOptions) Validate() error {
+	switch opts.Policy {
+	case "", PolicyLatestOnly, PolicyBlock, PolicyRingBuffer:
+	default:
+		return fmt.Errorf("unknown drop policy %q", opts.Policy)
+	}
+	if opts.Buffer < 0 {
+		return fmt.Errorf("subscriber buffer must not be negative, got %d", opts.Buffer)
+	}
+	return nil
+}
+
+// EventBus delivers the events of a simulation to any number of subscribers.
+// Each subscriber has its own buffer and drop policy, see SubscribeOptions.
+type EventBus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]*subscriber
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int]*subscriber)}
+}
+
+// subscriber is a subscription to the bus.
+// Block subscribers are sent to by Publish directly,
+// the other ones are delivered by a pump goroutine from their queue.
+type subscriber struct {
+	policy DropPolicy
+	buffer int
+	types  map[EventType]bool
+
+	out       chan Event
+	cancelled chan struct{}
+	cancel    sync.Once
+
+	sendMu sync.Mutex // held by Publish while sending to a block subscriber
+
+	mu     sync.Mutex
+	queue  []Event
+	notify chan struct{}
+}
+
+// Subscribe registers a subscriber and returns the channel its events are delivered on,
+// along with a function cancelling the subscription and closing the channel.
+// It returns an error on invalid options, see SubscribeOptions.Validate.
+func (b *EventBus) Subscribe(opts SubscribeOptions) (<-chan Event, func(), error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	s := &subscriber{
+		policy:    opts.Policy,
+		buffer:    opts.Buffer,
+		cancelled: make(chan struct{}),
+	}
+	if s.policy == "" {
+		s.policy = PolicyRingBuffer
+	}
+	if len(opts.Types) > 0 {
+		s.types = make(map[EventType]bool, len(opts.Types))
+		for _, t := range opts.Types {
+			s.types[t] = true
+		}
+	}
+
+	if s.policy == PolicyBlock {
+		s.out = make(chan Event, s.buffer)
+	} else {
+		if s.buffer == 0 {
+			s.buffer = defaultBufferSize
+		}
+		s.out = make(chan Event)
+		s.notify = make(chan struct{}, 1)
+		go s.pump()
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = s
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+		s.close()
+	}
+
+	return s.out, cancel, nil
+}
+
+// Publish delivers an event to every subscriber interested in its type.
+// It only blocks on the subscribers with PolicyBlock.
+func (b *EventBus) Publish(e Event) {
+	// deliver outside of the lock, subscribers may cancel while a block subscriber is waited for
+	b.mu.Lock()
+	subs := make([]*subscriber, 0, len(b.subs))
+	for _, s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		if s.types != nil && !s.types[e.Type] {
+			continue
+		}
+		if s.policy == PolicyBlock {
+			s.send(e)
+		} else {
+			s.enqueue(e)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers
+func (b *EventBus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// send sends an event to a block subscriber, unless it is cancelled meanwhile
+func (s *subscriber) send(e Event) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	select {
+	case <-s.cancelled:
+		return
+	default:
+	}
+
+	select {
+	case s.out <- e:
+	case <-s.cancelled:
+	}
+}
+
+// enqueue queues an event following the drop policy and wakes the pump up
+func (s *subscriber) enqueue(e Event) {
+	s.mu.Lock()
+	if s.policy == PolicyLatestOnly {
+		s.queue = append(s.queue[:0], e)
+	} else {
+		if len(s.queue) == s.buffer {
+			s.queue = s.queue[1:]
+		}
+		s.queue = append(s.queue, e)
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pump delivers the queued events until the subscription is cancelled
+func (s *subscriber) pump() {
+	defer close(s.out)
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.notify:
+				continue
+			case <-s.cancelled:
+				return
+			}
+		}
+		e := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- e:
+		case <-s.cancelled:
+			return
+		}
+	}
+}
+
+// close cancels the subscription, the channel is closed once nothing can be sent to it anymore
+func (s *subscriber) close() {
+	s.cancel.Do(func() {
+		close(s.cancelled)
+		if s.policy == PolicyBlock {
+			s.sendMu.Lock()
+			close(s.out)
+			s.sendMu.Unlock()
+		}
+	})
+}
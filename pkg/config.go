@@ -0,0 +1,225 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// AppCfg is the configuration for the app
+type AppCfg struct {
+	NumAliens     int    // Number of aliens landing on the map
+	MaxMoves      int    // Max number of moves allowed for each alien
+	MapInputFile  string // Map input filepath
+	LogFile       string // Log filepath, logs to stdout if empty
+	MapOutputFile string // Map output filepath
+	UseDelay      bool   // Use delay to slow down the simulation for observation
+	DelayMS       int    // Delay in milliseconds to slow down the simulation for observation
+	Seed          int64  // Seed of the random source, 0 picks a time based seed
+	JournalFile   string // Journal filepath, events are not recorded if empty
+	Format        string // Output format of the result: table, json, yaml or csv
+	Strict        bool   // Fail on any map diagnostic instead of reading the map leniently
+	Directions    string // Direction vocabulary of maps without a directions header, see ParseDirections
+
+	Strategy        string   // Movement strategy of the aliens, see ParseStrategy
+	AlienStrategies []string // Per alien movement strategies, as <alien id>=<strategy>
+	Factions        string   // Factions of the aliens, see ParseFactions
+
+	Waves          int      // Number of reinforcement waves landing after the first aliens, see App.LandWave
+	WaveSize       int      // Number of aliens landing in each wave
+	WaveEvery      int      // Number of ticks between two waves
+	WaveCities     []string // Cities the waves land in, in turn, random cities if empty
+	ReproduceAfter int      // Number of moves after which an alien gives birth, no reproduction if 0
+
+	NumDefenders     int      // Number of defenders landing on the map, see Defender
+	DefenderCities   []string // Cities the defenders land in, in turn, random cities if empty
+	DefenderStrategy string   // Movement strategy of the defenders, see ParseStrategy
+
+	Rules          CollisionRules // Rules applied when aliens meet in a city
+	TickMode       TickMode       // How aliens move during a tick, sequential if empty
+	RoadEncounters bool           // Aliens crossing on a road fight, requires the simultaneous tick mode
+
+	ResumeFile   string // Snapshot to resume instead of reading the map and landing aliens, see App.Restore
+	SnapshotFile string // Snapshot written by Start when the run ends, see App.Snapshot
+
+	Logger *logger.Logger // Logger used instead of LogFile when set, for embedding
+}
+
+// Validate returns an error if the configuration cannot be used to run a simulation
+func (cfg *AppCfg) Validate() error {
+	if cfg.NumAliens < 0 {
+		return fmt.Errorf("invalid config: number of aliens must not be negative, got %d", cfg.NumAliens)
+	}
+
+	if cfg.MaxMoves <= 0 {
+		return fmt.Errorf("invalid config: max moves must be positive, got %d", cfg.MaxMoves)
+	}
+
+	if cfg.MapInputFile == "" {
+		return fmt.Errorf("invalid config: map input file is required")
+	}
+
+	if cfg.UseDelay && cfg.DelayMS < 0 {
+		return fmt.Errorf("invalid config: delay must not be negative, got %d ms", cfg.DelayMS)
+	}
+
+	if _, err := ParseStrategy(cfg.Strategy); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	alienStrategies, err := ParseAlienStrategies(cfg.AlienStrategies)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	for id := range alienStrategies {
+		if id >= cfg.NumAliens {
+			return fmt.Errorf("invalid config: strategy set for alien %d but only %d aliens land", id, cfg.NumAliens)
+		}
+	}
+
+	if _, err := ParseFactions(cfg.Factions); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if cfg.Waves < 0 {
+		return fmt.Errorf("invalid config: number of waves must not be negative, got %d", cfg.Waves)
+	}
+	if cfg.Waves > 0 && cfg.WaveSize <= 0 {
+		return fmt.Errorf("invalid config: wave size must be positive, got %d", cfg.WaveSize)
+	}
+	if cfg.Waves > 0 && cfg.WaveEvery <= 0 {
+		return fmt.Errorf("invalid config: ticks between waves must be positive, got %d", cfg.WaveEvery)
+	}
+	if cfg.ReproduceAfter < 0 {
+		return fmt.Errorf("invalid config: moves before reproducing must not be negative, got %d", cfg.ReproduceAfter)
+	}
+
+	if cfg.NumDefenders < 0 {
+		return fmt.Errorf("invalid config: number of defenders must not be negative, got %d", cfg.NumDefenders)
+	}
+	if _, err := ParseStrategy(cfg.DefenderStrategy); err != nil {
+		return fmt.Errorf("invalid config: defenders: %w", err)
+	}
+
+	if _, err := ParseDirections(cfg.Directions); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if err := cfg.Rules.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	mode, err := ParseTickMode(string(cfg.TickMode))
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if cfg.RoadEncounters && mode != TickSimultaneous {
+		return fmt.Errorf("invalid config: road encounters require the %s tick mode", TickSimultaneous)
+	}
+
+	if cfg.ResumeFile != "" && cfg.JournalFile != "" {
+		return fmt.Errorf("invalid config: a resumed simulation cannot be journaled, its journal would miss the events before the snapshot")
+	}
+
+	if err := ValidateFormat(cfg.Format); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigFromFlags builds the app configuration from the flags defined by App.DefineFlags
+func ConfigFromFlags(cmd *cobra.Command) (AppCfg, error) {
+	flags := &flagReader{cmd: cmd}
+
+	cfg := AppCfg{
+		NumAliens:     flags.Int("aliens"),
+		MaxMoves:      flags.Int("max_moves"),
+		MapInputFile:  flags.String("input"),
+		MapOutputFile: flags.String("output"),
+		LogFile:       flags.String("log"),
+		UseDelay:      flags.Bool("delay"),
+		DelayMS:       flags.Int("delay_ms"),
+		Seed:          flags.Int64("seed"),
+		JournalFile:   flags.String("journal"),
+		Format:        flags.String("format"),
+		Strict:        flags.Bool("strict"),
+		Directions:    flags.String("directions"),
+
+		Strategy:        flags.String("strategy"),
+		AlienStrategies: flags.StringArray("alien_strategy"),
+		Factions:        flags.String("factions"),
+
+		Waves:          flags.Int("waves"),
+		WaveSize:       flags.Int("wave_size"),
+		WaveEvery:      flags.Int("wave_every"),
+		WaveCities:     flags.StringArray("wave_city"),
+		ReproduceAfter: flags.Int("reproduce_after"),
+
+		NumDefenders:     flags.Int("defenders"),
+		DefenderCities:   flags.StringArray("defender_city"),
+		DefenderStrategy: flags.String("defender_strategy"),
+
+		Rules: CollisionRules{
+			Threshold:      flags.Int("threshold"),
+			SurvivalChance: flags.Float64("survival_chance"),
+			Mode:           CollisionMode(flags.String("collision_mode")),
+			CityHitPoints:  flags.Int("city_hp"),
+		},
+		TickMode:       TickMode(flags.String("tick_mode")),
+		RoadEncounters: flags.Bool("road_encounters"),
+		ResumeFile:     flags.String("resume"),
+		SnapshotFile:   flags.String("snapshot"),
+	}
+
+	return cfg, flags.err
+}
+
+// flagReader reads command flags, keeping the first error encountered
+type flagReader struct {
+	cmd *cobra.Command
+	err error
+}
+
+func (r *flagReader) check(name string, err error) {
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("error reading flag %s: %w", name, err)
+	}
+}
+
+func (r *flagReader) Int(name string) int {
+	v, err := r.cmd.Flags().GetInt(name)
+	r.check(name, err)
+	return v
+}
+
+func (r *flagReader) Int64(name string) int64 {
+	v, err := r.cmd.Flags().GetInt64(name)
+	r.check(name, err)
+	return v
+}
+
+func (r *flagReader) String(name string) string {
+	v, err := r.cmd.Flags().GetString(name)
+	r.check(name, err)
+	return v
+}
+
+func (r *flagReader) Bool(name string) bool {
+	v, err := r.cmd.Flags().GetBool(name)
+	r.check(name, err)
+	return v
+}
+
+func (r *flagReader) StringArray(name string) []string {
+	v, err := r.cmd.Flags().GetStringArray(name)
+	r.check(name, err)
+	return v
+}
+
+func (r *flagReader) Float64(name string) float64 {
+	v, err := r.cmd.Flags().GetFloat64(name)
+	r.check(name, err)
+	return v
+}
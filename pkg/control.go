@@ -0,0 +1,138 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Pause pauses the main loop between two ticks, see Resume and RequestStep
+func (a *App) Pause() {
+	atomic.StoreInt32(&a.paused, 1)
+}
+
+// Resume resumes a paused main loop
+func (a *App) Resume() {
+	atomic.StoreInt32(&a.paused, 0)
+	a.wakeUp()
+}
+
+// IsPaused returns true if the main loop is paused
+func (a *App) IsPaused() bool {
+	return atomic.LoadInt32(&a.paused) == 1
+}
+
+// RequestStep makes a paused main loop execute a single tick
+func (a *App) RequestStep() {
+	atomic.AddInt32(&a.pendingSteps, 1)
+	a.wakeUp()
+}
+
+// takeStepRequest consumes a step requested with RequestStep, if any
+func (a *App) takeStepRequest() bool {
+	for {
+		pending := atomic.LoadInt32(&a.pendingSteps)
+		if pending <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&a.pendingSteps, pending, pending-1) {
+			return true
+		}
+	}
+}
+
+// SetDelay sets the delay between two ticks of the main loop, 0 disables it.
+// It can be changed while running.
+func (a *App) SetDelay(ms int) {
+	if ms < 0 {
+		ms = 0
+	}
+	atomic.StoreInt64(&a.delayMS, int64(ms))
+}
+
+// Delay returns the delay in milliseconds between two ticks of the main loop
+func (a *App) Delay() int {
+	return int(atomic.LoadInt64(&a.delayMS))
+}
+
+// IsRunning returns true while the main loop is running
+func (a *App) IsRunning() bool {
+	return atomic.LoadInt32(&a.running) == 1
+}
+
+// Restart stops the running simulation, if any, and initializes a new one
+// with the same configuration and the given seed (0 picks a random seed).
+// The pause state and the delay are kept. Run must be called again afterwards.
+// A journaled simulation journals to a new file next to the first journal,
+// see nextJournalFile, so that the journals of the previous runs can still be replayed.
+func (a *App) Restart(seed int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.IsRunning() {
+		a.Stop()
+		<-a.exited
+	}
+
+	cfg := *a.Cfg
+	cfg.Seed = seed
+	cfg.ResumeFile = ""
+	cfg.UseDelay = a.Delay() > 0
+	cfg.DelayMS = a.Delay()
+	cfg.Logger = a.logger // the log file is not truncated
+
+	// Close the journal of a simulation that was never run to its end
+	if a.journal != nil {
+		if err := a.journal.Close(); err != nil {
+			a.logger.Logf("error: journal: %v", err)
+		}
+		a.journal = nil
+	}
+	if cfg.JournalFile != "" {
+		if a.firstJournal == "" {
+			a.firstJournal = cfg.JournalFile
+		}
+		cfg.JournalFile = nextJournalFile(a.firstJournal)
+	}
+
+	return a.InitWithConfig(cfg)
+}
+
+// nextJournalFile returns the first journal file name of the form <name>.<n><ext>
+// not taken yet, journal.jsonl is followed by journal.1.jsonl, journal.2.jsonl...
+func nextJournalFile(filename string) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s.%d%s", base, n, ext)
+		if _, err := os.Stat(candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+	}
+}
+
+// wakeUp wakes a paused main loop up to check its controls again
+func (a *App) wakeUp() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// waitControl waits for a paused main loop to be woken up,
+// serving the snapshot requests meanwhile
+func (a *App) waitControl(ctx context.Context) error {
+	select {
+	case <-a.wake:
+		return nil
+	case req := <-a.snapshotReqs:
+		req.err <- a.Snapshot(req.w)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,221 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Defender is a human defense force protecting the cities from the aliens.
+// Defenders in a city destroy the aliens too few to start an encounter there,
+// and fall one by one holding the city against the encounters, see ResolveCollisions.
+type Defender struct {
+	ID          int
+	CurrentCity *City
+	Moved       int // distance travelled, in ticks of road
+	Arrival     int // tick at which the defender reaches CurrentCity while on the road, 0 once in it
+}
+
+// InTransit returns true if the defender is still on the road to its current city
+func (d *Defender) InTransit() bool {
+	return d.Arrival > 0
+}
+
+// DefenderSet is a set of defenders
+type DefenderSet map[int]*Defender
+
+// IDs returns the IDs of the defenders in the set in ascending order
+func (s DefenderSet) IDs() []int {
+	ids := make([]int, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// createDefenders creates the defenders and stores them in the app
+func (a *App) createDefenders(numDefenders int) {
+	for i := 0; i < numDefenders; i++ {
+		a.State.Defenders[i] = &Defender{ID: i}
+	}
+}
+
+// PopulateMapWithDefenders assigns defenders to the configured cities in turn,
+// or to random cities if none is configured
+func (a *App) PopulateMapWithDefenders() error {
+	for i, id := range a.State.Defenders.IDs() {
+		city, err := a.defenderCity(i)
+		if err != nil {
+			return err
+		}
+
+		a.stateCtrl.emit(Event{Type: EventDefenderLanded, Defender: id, City: city.Name})
+		a.stateCtrl.landDefender(a.State.Defenders[id], city)
+	}
+	return nil
+}
+
+// defenderCity returns the city the i-th defender lands in
+func (a *App) defenderCity(i int) (*City, error) {
+	if len(a.Cfg.DefenderCities) == 0 {
+		return a.getRandomCity(), nil
+	}
+
+	name := a.Cfg.DefenderCities[i%len(a.Cfg.DefenderCities)]
+	city, found := a.State.WorldMap.Cities[name]
+	if !found {
+		return nil, fmt.Errorf("city %s of the defenders does not exist in world map", name)
+	}
+	return city, nil
+}
+
+// landDefender places a defender in a city
+func (sc *StateController) landDefender(defender *Defender, city *City) {
+	defender.CurrentCity = city
+	defender.Arrival = 0
+	sc.placeDefender(defender)
+}
+
+// placeDefender adds a defender to the locations of its current city
+func (sc *StateController) placeDefender(defender *Defender) {
+	if sc.app.State.DefenderLocations == nil {
+		sc.app.State.DefenderLocations = make(map[*City]DefenderSet)
+	}
+	if location, found := sc.app.State.DefenderLocations[defender.CurrentCity]; found {
+		location[defender.ID] = defender
+	} else {
+		sc.app.State.DefenderLocations[defender.CurrentCity] = DefenderSet{defender.ID: defender}
+	}
+}
+
+// DestroyDefender destroys a defender and removes it from the city it is currently in.
+func (sc *StateController) DestroyDefender(defenderID int) error {
+	defender, exists := sc.app.State.Defenders[defenderID]
+	if !exists {
+		return fmt.Errorf("defender %d does not exist", defenderID)
+	}
+
+	e := Event{Type: EventDefenderDestroyed, Defender: defenderID}
+	if defender.CurrentCity != nil {
+		e.City = defender.CurrentCity.Name
+	}
+	sc.emit(e)
+
+	delete(sc.app.State.DefenderLocations[defender.CurrentCity], defenderID)
+	delete(sc.app.State.Defenders, defenderID)
+	return nil
+}
+
+// destroyDefendersOf destroys the defenders in a city and on the road to it
+func (sc *StateController) destroyDefendersOf(city *City) {
+	for _, id := range sc.app.State.Defenders.IDs() {
+		if sc.app.State.Defenders[id].CurrentCity == city {
+			sc.DestroyDefender(id)
+		}
+	}
+	delete(sc.app.State.DefenderLocations, city)
+}
+
+// DefenderStrategy returns the movement strategy of the defenders, uniform if none is set.
+func (sc *StateController) DefenderStrategy() MovementStrategy {
+	if sc.defenderStrategy == nil {
+		return UniformStrategy{}
+	}
+	return sc.defenderStrategy
+}
+
+// SetDefenderStrategy sets the movement strategy of the defenders.
+func (sc *StateController) SetDefenderStrategy(strategy MovementStrategy) {
+	sc.defenderStrategy = strategy
+}
+
+// NextDefenderCity returns the city a defender moves to next following the strategy
+// of the defenders, or nil if it stays put. The state is left untouched.
+// The alien strategies apply to the defenders as they are: SeekStrategy rushes to the
+// nearest aliens, where the defenders repel the lone ones and fall holding the crowded cities,
+// AvoidCrowdedStrategy keeps to the quietest cities, and a WeightedStrategy forbidding
+// every road left garrisons the defender in its city.
+func (sc *StateController) NextDefenderCity(defender *Defender) (*City, error) {
+	if defender.InTransit() {
+		return nil, nil
+	}
+
+	next, err := sc.DefenderStrategy().NextCity(sc, defender.CurrentCity, sc.app.rng)
+	if err != nil || next == nil {
+		return nil, err
+	}
+
+	// the terrain holds the defenders back as well, see NextCity
+	if passability := next.Terrain.Passability(); passability < 1 && sc.app.rng.Float64() >= passability {
+		return nil, nil
+	}
+	return next, nil
+}
+
+// MoveDefenders moves every defender once in ID order, after the aliens,
+// then the defenders reaching the end of their road arrive.
+// Defenders without neighbours stay put, the other errors are joined.
+func (sc *StateController) MoveDefenders() error {
+	var errs []error
+	for _, id := range sc.app.State.Defenders.IDs() {
+		defender := sc.app.State.Defenders[id]
+		next, err := sc.NextDefenderCity(defender)
+		if err != nil {
+			if !errors.Is(err, ErrNoNeighbours) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if next != nil {
+			sc.emit(Event{Type: EventDefenderMoved, Defender: id, From: defender.CurrentCity.Name, City: next.Name})
+			sc.moveDefender(defender, next)
+		}
+	}
+
+	for _, id := range sc.app.State.Defenders.IDs() {
+		defender := sc.app.State.Defenders[id]
+		if defender.InTransit() && defender.Arrival <= sc.app.State.Tick {
+			sc.emit(Event{Type: EventDefenderArrived, Defender: id, City: defender.CurrentCity.Name})
+			sc.landDefender(defender, defender.CurrentCity)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// moveDefender moves a defender to the given city, see moveAlien
+func (sc *StateController) moveDefender(defender *Defender, nextCity *City) {
+	distance := defender.CurrentCity.DistanceTo(nextCity)
+
+	delete(sc.app.State.DefenderLocations[defender.CurrentCity], defender.ID)
+	defender.Moved += distance
+	if distance > 1 {
+		defender.CurrentCity = nextCity
+		defender.Arrival = sc.app.State.Tick + distance - 1
+		return
+	}
+	sc.landDefender(defender, nextCity)
+}
+
+// repelAliens makes the defenders of a city destroy its aliens when they are
+// too few to start an encounter and do not outnumber the defenders.
+func (sc *StateController) repelAliens(city *City) error {
+	defenders := sc.app.State.DefenderLocations[city]
+	aliens := sc.app.State.AlienLocations[city]
+	if len(defenders) == 0 || len(aliens) == 0 || len(aliens) > len(defenders) {
+		return nil
+	}
+
+	defenderIDs := defenders.IDs()
+	for _, id := range aliens.IDs() {
+		sc.emit(Event{Type: EventAlienRepelled, Alien: id, City: city.Name, Defenders: defenderIDs})
+		if err := sc.DestroyAlien(id); err != nil {
+			return err
+		}
+		if sc.printer != nil {
+			sc.printer.Log(fmt.Sprintf("Alien %d has been destroyed by the defenders of %s %v", id, city.Name, defenderIDs))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Direction vocabulary presets, see ParseDirections
+const (
+	DirectionsCompass  = "compass"  // north, south, east, west
+	DirectionsCompass8 = "compass8" // compass plus northeast, southwest, northwest, southeast
+	DirectionsLayers   = "layers"   // up, down
+)
+
+// DirectionsHeader starts the map file line declaring the direction vocabulary of the map,
+// e.g. "@directions compass8 in=out", it must come before the cities
+const DirectionsHeader = "@directions"
+
+var directionPresets = map[string][][2]string{
+	DirectionsCompass:  {{"north", "south"}, {"east", "west"}},
+	DirectionsCompass8: {{"north", "south"}, {"east", "west"}, {"northeast", "southwest"}, {"northwest", "southeast"}},
+	DirectionsLayers:   {{"up", "down"}},
+}
+
+// Directions is a direction vocabulary: the directions a map can use, each one with its opposite.
+// A road declared in a direction implies the road back in the opposite direction.
+// Directions are immutable once created.
+type Directions struct {
+	order    []string // canonical order, in which neighbours are written
+	opposite map[string]string
+	spec     []string // presets and pairs it was parsed from
+}
+
+// compassDirections is the vocabulary of the maps not declaring one
+var compassDirections, _ = ParseDirections(DirectionsCompass)
+
+// DefaultDirections returns the compass vocabulary: north, south, east and west
+func DefaultDirections() *Directions {
+	return compassDirections
+}
+
+// ParseDirections parses a direction vocabulary: presets (compass, compass8, layers)
+// and pairs of opposite directions like in=out, separated by commas or spaces.
+// A direction can be its own opposite, like portal=portal.
+// An empty spec is the compass vocabulary.
+func ParseDirections(spec string) (*Directions, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	if len(fields) == 0 {
+		fields = []string{DirectionsCompass}
+	}
+
+	d := &Directions{opposite: make(map[string]string)}
+	add := func(direction, opposite string) error {
+		if direction == "" || opposite == "" || strings.ContainsAny(direction+opposite, "=:@") {
+			return fmt.Errorf("invalid direction pair %s=%s", direction, opposite)
+		}
+		for _, pair := range [][2]string{{direction, opposite}, {opposite, direction}} {
+			if previous, found := d.opposite[pair[0]]; found && previous != pair[1] {
+				return fmt.Errorf("direction %s cannot be the opposite of both %s and %s", pair[0], previous, pair[1])
+			}
+		}
+		if _, found := d.opposite[direction]; !found {
+			d.order = append(d.order, direction)
+			if opposite != direction {
+				d.order = append(d.order, opposite)
+			}
+		}
+		d.opposite[direction], d.opposite[opposite] = opposite, direction
+		return nil
+	}
+
+	for _, field := range fields {
+		if d.declares(field) {
+			continue
+		}
+		if pairs, found := directionPresets[field]; found {
+			for _, pair := range pairs {
+				if err := add(pair[0], pair[1]); err != nil {
+					return nil, err
+				}
+			}
+			d.spec = append(d.spec, field)
+			continue
+		}
+
+		parts := strings.Split(field, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid directions %q, expected compass, compass8, layers or <direction>=<opposite>", field)
+		}
+		if err := add(parts[0], parts[1]); err != nil {
+			return nil, err
+		}
+		d.spec = append(d.spec, field)
+	}
+
+	return d, nil
+}
+
+// declares returns true if the preset or pair is already declared
+func (d *Directions) declares(field string) bool {
+	for _, declared := range d.spec {
+		if declared == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Opposite returns the opposite of a direction, or an empty string if it is not in the vocabulary
+func (d *Directions) Opposite(direction string) string {
+	return d.opposite[direction]
+}
+
+// Has returns true if the direction is in the vocabulary
+func (d *Directions) Has(direction string) bool {
+	_, found := d.opposite[direction]
+	return found
+}
+
+// Names returns the directions in canonical order
+func (d *Directions) Names() []string {
+	return append([]string(nil), d.order...)
+}
+
+// IsDefault returns true for the compass vocabulary, which maps do not need to declare
+func (d *Directions) IsDefault() bool {
+	return d.String() == DirectionsCompass
+}
+
+// String returns the vocabulary in the format read by ParseDirections
+func (d *Directions) String() string {
+	return strings.Join(d.spec, " ")
+}
+
+// Sort sorts directions in canonical order,
+// directions out of the vocabulary come last in alphabetical order
+func (d *Directions) Sort(directions []string) {
+	rank := make(map[string]int, len(d.order))
+	for i, direction := range d.order {
+		rank[direction] = i
+	}
+	rankOf := func(direction string) int {
+		if r, found := rank[direction]; found {
+			return r
+		}
+		return len(d.order)
+	}
+
+	sort.SliceStable(directions, func(i, j int) bool {
+		ri, rj := rankOf(directions[i]), rankOf(directions[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return directions[i] < directions[j]
+	})
+}
+
+// parseDirectionsHeader parses the directions declared by a map file line,
+// found is false if the line is not a directions header
+func parseDirectionsHeader(fields []string) (directions *Directions, found bool, err error) {
+	if len(fields) == 0 || fields[0] != DirectionsHeader {
+		return nil, false, nil
+	}
+	if len(fields) == 1 {
+		return nil, true, fmt.Errorf("%s declares no directions", DirectionsHeader)
+	}
+
+	directions, err = ParseDirections(strings.Join(fields[1:], " "))
+	return directions, true, err
+}
@@ -0,0 +1,113 @@
+package simulation
+
+import "fmt"
+
+// EventType is the type of a state transition recorded in the journal
+type EventType string
+
+const (
+	EventSimulationStarted EventType = "SimulationStarted"
+	EventAlienLanded       EventType = "AlienLanded"
+	EventAlienBorn         EventType = "AlienBorn"
+	EventWaveLanded        EventType = "WaveLanded"
+	EventAlienMoved        EventType = "AlienMoved"
+	EventAlienArrived      EventType = "AlienArrived"
+	EventRoadEncounter     EventType = "RoadEncounter"
+	EventCityDamaged       EventType = "CityDamaged"
+	EventCityDestroyed     EventType = "CityDestroyed"
+	EventAlienDestroyed    EventType = "AlienDestroyed"
+	EventDefenderLanded    EventType = "DefenderLanded"
+	EventDefenderMoved     EventType = "DefenderMoved"
+	EventDefenderArrived   EventType = "DefenderArrived"
+	EventAlienRepelled     EventType = "AlienRepelled"
+	EventCityDefended      EventType = "CityDefended"
+	EventDefenderDestroyed EventType = "DefenderDestroyed"
+	EventTickEnded         EventType = "TickEnded"
+	EventSimulationEnded   EventType = "SimulationEnded"
+)
+
+// Event is a single state transition of the simulation.
+// Only the fields relevant to the event type are set:
+//
+//	SimulationStarted: Map, Seed, Directions, MaxMoves
+//	AlienLanded:       Alien, City, Faction
+//	AlienBorn:         Alien, City, Parent, Faction
+//	WaveLanded:        Aliens, landed by the AlienLanded events before it
+//	AlienMoved:        Alien, From, City
+//	AlienArrived:      Alien, City
+//	RoadEncounter:     From, City, Aliens
+//	CityDamaged:       City, Aliens
+//	CityDestroyed:     City, Aliens
+//	AlienDestroyed:    Alien, City
+//	DefenderLanded:    Defender, City
+//	DefenderMoved:     Defender, From, City
+//	DefenderArrived:   Defender, City
+//	AlienRepelled:     Alien, City, Defenders
+//	CityDefended:      City, Aliens, Defender
+//	DefenderDestroyed: Defender, City
+//	TickEnded:         no field, Tick is the tick that ended
+//	SimulationEnded:   Reason
+type Event struct {
+	Tick   int       `json:"tick"`
+	Type   EventType `json:"type"`
+	Alien  int       `json:"alien"`
+	City   string    `json:"city,omitempty"`
+	From   string    `json:"from,omitempty"`
+	Aliens []int     `json:"aliens,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	Map    string    `json:"map,omitempty"`
+	Seed   int64     `json:"seed,omitempty"`
+
+	Defender  int   `json:"defender"`
+	Defenders []int `json:"defenders,omitempty"`
+
+	Faction string `json:"faction,omitempty"` // faction of a landed or born alien, see ParseFactions
+	Parent  int    `json:"parent,omitempty"`  // parent of a born alien, see StateController.ReproduceAliens
+
+	Directions string `json:"directions,omitempty"` // vocabulary of a map without directions header
+	MaxMoves   int    `json:"max_moves,omitempty"`  // movement limit of the aliens
+}
+
+// String returns a human readable representation of the event
+func (e Event) String() string {
+	switch e.Type {
+	case EventSimulationStarted:
+		return fmt.Sprintf("[%d] simulation started with map %s and seed %d", e.Tick, e.Map, e.Seed)
+	case EventAlienLanded:
+		return fmt.Sprintf("[%d] alien %d landed in %s", e.Tick, e.Alien, e.City)
+	case EventAlienBorn:
+		return fmt.Sprintf("[%d] alien %d born to alien %d in %s", e.Tick, e.Alien, e.Parent, e.City)
+	case EventWaveLanded:
+		return fmt.Sprintf("[%d] wave of aliens %v landed", e.Tick, e.Aliens)
+	case EventAlienMoved:
+		return fmt.Sprintf("[%d] alien %d moved from %s to %s", e.Tick, e.Alien, e.From, e.City)
+	case EventAlienArrived:
+		return fmt.Sprintf("[%d] alien %d arrived in %s", e.Tick, e.Alien, e.City)
+	case EventRoadEncounter:
+		return fmt.Sprintf("[%d] aliens %v met on the road between %s and %s", e.Tick, e.Aliens, e.From, e.City)
+	case EventCityDamaged:
+		return fmt.Sprintf("[%d] city %s attacked by aliens %v", e.Tick, e.City, e.Aliens)
+	case EventCityDestroyed:
+		return fmt.Sprintf("[%d] city %s destroyed by aliens %v", e.Tick, e.City, e.Aliens)
+	case EventAlienDestroyed:
+		return fmt.Sprintf("[%d] alien %d destroyed in %s", e.Tick, e.Alien, e.City)
+	case EventDefenderLanded:
+		return fmt.Sprintf("[%d] defender %d landed in %s", e.Tick, e.Defender, e.City)
+	case EventDefenderMoved:
+		return fmt.Sprintf("[%d] defender %d moved from %s to %s", e.Tick, e.Defender, e.From, e.City)
+	case EventDefenderArrived:
+		return fmt.Sprintf("[%d] defender %d arrived in %s", e.Tick, e.Defender, e.City)
+	case EventAlienRepelled:
+		return fmt.Sprintf("[%d] alien %d repelled by defenders %v in %s", e.Tick, e.Alien, e.Defenders, e.City)
+	case EventCityDefended:
+		return fmt.Sprintf("[%d] city %s held by defender %d against aliens %v", e.Tick, e.City, e.Defender, e.Aliens)
+	case EventDefenderDestroyed:
+		return fmt.Sprintf("[%d] defender %d destroyed in %s", e.Tick, e.Defender, e.City)
+	case EventTickEnded:
+		return fmt.Sprintf("[%d] tick ended", e.Tick)
+	case EventSimulationEnded:
+		return fmt.Sprintf("[%d] simulation ended: %s", e.Tick, e.Reason)
+	default:
+		return fmt.Sprintf("[%d] unknown event %s", e.Tick, e.Type)
+	}
+}
@@ -0,0 +1,196 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FactionShare is the share of the aliens landing in a faction, see ParseFactions
+type FactionShare struct {
+	Name   string
+	Weight float64
+}
+
+// ParseFactions parses a factions spec of the form <name>:<share>,...
+// where the shares are either the number of aliens of each faction, or ratios.
+// An empty spec has no faction.
+func ParseFactions(spec string) ([]FactionShare, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var shares []FactionShare
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(field), ":")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid factions %q: expected <name>:<share>, got %q", spec, field)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("invalid factions %q: faction %s is declared more than once", spec, name)
+		}
+		seen[name] = true
+
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("invalid factions %q: invalid share %q of %s", spec, value, name)
+		}
+		shares = append(shares, FactionShare{Name: name, Weight: weight})
+	}
+
+	total := 0.0
+	for _, share := range shares {
+		total += share.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("invalid factions %q: every share is zero", spec)
+	}
+	return shares, nil
+}
+
+// AssignFactions returns the faction of each of n aliens, in ID order.
+// Shares summing up to n are counts, other shares are ratios of n,
+// the aliens left over by the rounding go to the largest remainders.
+func AssignFactions(shares []FactionShare, n int) []string {
+	if len(shares) == 0 {
+		return make([]string, n)
+	}
+
+	total := 0.0
+	for _, share := range shares {
+		total += share.Weight
+	}
+
+	counts := make([]int, len(shares))
+	remainders := make([]float64, len(shares))
+	assigned := 0
+	for i, share := range shares {
+		exact := share.Weight / total * float64(n)
+		counts[i] = int(math.Floor(exact))
+		remainders[i] = exact - float64(counts[i])
+		assigned += counts[i]
+	}
+
+	order := make([]int, len(shares))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := 0; assigned < n; i++ {
+		counts[order[i%len(order)]]++
+		assigned++
+	}
+
+	factions := make([]string, 0, n)
+	for i, share := range shares {
+		for j := 0; j < counts[i]; j++ {
+			factions = append(factions, share.Name)
+		}
+	}
+	return factions
+}
+
+// assignFactions assigns the configured factions to the aliens, the config has been validated
+func (a *App) assignFactions() {
+	shares, _ := ParseFactions(a.Cfg.Factions)
+	if len(shares) == 0 {
+		return
+	}
+
+	ids := a.State.Aliens.IDs()
+	for i, faction := range AssignFactions(shares, len(ids)) {
+		a.State.Aliens[ids[i]].Faction = faction
+	}
+}
+
+// countLanding counts a landed alien in the statistics of its faction
+func (sc *StateController) countLanding(alien *Alien) {
+	if alien.Faction == "" {
+		return
+	}
+	if sc.app.State.FactionLanded == nil {
+		sc.app.State.FactionLanded = make(map[string]int)
+	}
+	sc.app.State.FactionLanded[alien.Faction]++
+}
+
+// factionSurvivors returns the aliens surviving an encounter between factions:
+// aliens of a single faction cooperate and all survive, otherwise the most
+// numerous faction wins the battle, ties being broken at random, and the others perish.
+// found is false if none of the aliens belongs to a faction, see CollisionRules.
+func (sc *StateController) factionSurvivors(ids []int) (survives map[int]bool, found bool) {
+	sizes := make(map[string]int)
+	for _, id := range ids {
+		faction := sc.app.State.Aliens[id].Faction
+		found = found || faction != ""
+		sizes[faction]++
+	}
+	if !found {
+		return nil, false
+	}
+
+	largest := 0
+	var winners []string
+	for _, faction := range sortedKeys(sizes) {
+		switch {
+		case sizes[faction] > largest:
+			largest, winners = sizes[faction], []string{faction}
+		case sizes[faction] == largest:
+			winners = append(winners, faction)
+		}
+	}
+
+	winner := winners[0]
+	if len(winners) > 1 {
+		winner = winners[sc.app.rng.Intn(len(winners))]
+	}
+
+	survives = make(map[int]bool, len(ids))
+	for _, id := range ids {
+		survives[id] = sc.app.State.Aliens[id].Faction == winner
+	}
+	return survives, true
+}
+
+// FactionResult is the survival statistics of a faction
+type FactionResult struct {
+	Name      string  `json:"name" yaml:"name"`
+	Landed    int     `json:"landed" yaml:"landed"`
+	Survivors int     `json:"survivors" yaml:"survivors"`
+	Survival  float64 `json:"survival_pct" yaml:"survival_pct"` // percentage of the landed aliens surviving
+}
+
+// factionResults returns the survival statistics of the factions sorted by name
+func (a *App) factionResults() []FactionResult {
+	survivors := make(map[string]int)
+	for _, alien := range a.State.Aliens {
+		if alien.Faction != "" {
+			survivors[alien.Faction]++
+		}
+	}
+
+	var results []FactionResult
+	for _, name := range sortedKeys(a.State.FactionLanded) {
+		res := FactionResult{Name: name, Landed: a.State.FactionLanded[name], Survivors: survivors[name]}
+		if res.Landed > 0 {
+			res.Survival = 100 * float64(res.Survivors) / float64(res.Landed)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// sortedKeys returns the keys of a map in ascending order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
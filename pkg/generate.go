@@ -0,0 +1,358 @@
+package simulation
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// MapKind is the shape of a generated map, see GenerateMap
+type MapKind string
+
+const (
+	MapGrid       MapKind = "grid"       // rectangular grid with holes
+	MapRandom     MapKind = "random"     // random connected graph
+	MapTree       MapKind = "tree"       // random tree, a single path between any two cities
+	MapRing       MapKind = "ring"       // single loop of cities
+	MapContinents MapKind = "continents" // clusters of cities linked by a single road
+)
+
+// GenerateCfg is the configuration of a generated map
+type GenerateCfg struct {
+	Kind       MapKind
+	Size       int     // Number of cities
+	Density    float64 // Share of the grid cells kept (grid) or chance of each extra road (random, continents), in (0, 1]
+	Seed       int64   // Seed of the random source, 0 picks a time based seed
+	Continents int     // Number of continents, continents only
+}
+
+// Validate returns an error if no map can be generated from the configuration
+func (cfg *GenerateCfg) Validate() error {
+	switch cfg.Kind {
+	case MapGrid, MapRandom, MapTree, MapContinents:
+	case MapRing:
+		if cfg.Size < 4 || cfg.Size%2 != 0 {
+			return fmt.Errorf("invalid generate config: a ring needs an even number of cities, at least 4, got %d", cfg.Size)
+		}
+	default:
+		return fmt.Errorf("invalid generate config: unknown map kind %q, expected grid, random, tree, ring or continents", cfg.Kind)
+	}
+
+	if cfg.Size <= 0 {
+		return fmt.Errorf("invalid generate config: size must be positive, got %d", cfg.Size)
+	}
+
+	if cfg.Density <= 0 || cfg.Density > 1 {
+		return fmt.Errorf("invalid generate config: density must be in (0, 1], got %v", cfg.Density)
+	}
+
+	if cfg.Kind == MapContinents && (cfg.Continents <= 0 || cfg.Continents > cfg.Size) {
+		return fmt.Errorf("invalid generate config: number of continents must be between 1 and the size, got %d", cfg.Continents)
+	}
+
+	return nil
+}
+
+// gridGraph is a map being generated: cities sit on grid cells and
+// roads link cities in the same row or column, so that the map is consistent
+type gridGraph struct {
+	rng   *rand.Rand
+	cells map[GridPoint]bool
+	roads map[[2]GridPoint]bool // ordered west to east or north to south
+}
+
+func newGridGraph(rng *rand.Rand) *gridGraph {
+	return &gridGraph{rng: rng, cells: make(map[GridPoint]bool), roads: make(map[[2]GridPoint]bool)}
+}
+
+// link adds a road between two cells of the same row or column
+func (g *gridGraph) link(a, b GridPoint) {
+	if b.X < a.X || b.Y < a.Y {
+		a, b = b, a
+	}
+	g.roads[[2]GridPoint{a, b}] = true
+}
+
+// sortedCells returns the cells in reading order, row by row
+func (g *gridGraph) sortedCells() []GridPoint {
+	cells := make([]GridPoint, 0, len(g.cells))
+	for p := range g.cells {
+		cells = append(cells, p)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+	return cells
+}
+
+// adjacent returns the four cells around a cell, east and south first
+func adjacent(p GridPoint) []GridPoint {
+	return []GridPoint{{p.X + 1, p.Y}, {p.X, p.Y + 1}, {p.X - 1, p.Y}, {p.X, p.Y - 1}}
+}
+
+// grow adds n cities to the graph, each one linked to a city next to it, starting from the origin.
+// The cities grow in random directions, inside the columns from minX to maxX.
+func (g *gridGraph) grow(origin GridPoint, n, minX, maxX int) {
+	type candidate struct{ cell, from GridPoint }
+	g.cells[origin] = true
+	frontier := []candidate{}
+	push := func(from GridPoint) {
+		for _, cell := range adjacent(from) {
+			if cell.X >= minX && cell.X <= maxX && !g.cells[cell] {
+				frontier = append(frontier, candidate{cell, from})
+			}
+		}
+	}
+	push(origin)
+
+	for added := 1; added < n && len(frontier) > 0; {
+		i := g.rng.Intn(len(frontier))
+		next := frontier[i]
+		frontier = RemoveSliceElement(frontier, i)
+		if g.cells[next.cell] {
+			continue
+		}
+
+		g.cells[next.cell] = true
+		g.link(next.from, next.cell)
+		push(next.cell)
+		added++
+	}
+}
+
+// connectNeighbours links every pair of cities next to each other with the given chance
+func (g *gridGraph) connectNeighbours(chance float64) {
+	for _, p := range g.sortedCells() {
+		for _, q := range adjacent(p)[:2] {
+			if g.cells[q] && !g.roads[[2]GridPoint{p, q}] && g.rng.Float64() < chance {
+				g.link(p, q)
+			}
+		}
+	}
+}
+
+// toMap names the cities in reading order and links them by direction
+func (g *gridGraph) toMap() *Map {
+	m := &Map{Cities: make(map[string]*City)}
+	names := newNameGenerator(g.rng)
+	cities := make(map[GridPoint]*City, len(g.cells))
+	for _, p := range g.sortedCells() {
+		city := &City{Name: names.next(), Neighbours: make(map[string]*City)}
+		cities[p] = city
+		m.AddCity(city)
+	}
+
+	for road := range g.roads {
+		a, b := cities[road[0]], cities[road[1]]
+		if road[0].Y == road[1].Y {
+			a.Neighbours["east"], b.Neighbours["west"] = b, a
+		} else {
+			a.Neighbours["south"], b.Neighbours["north"] = b, a
+		}
+	}
+	return m
+}
+
+// GenerateMap generates a map of the given kind, which can be written with WriteMap
+// and read back with ReadMapFromFile. The same configuration always generates the same map.
+// The cities sit on a grid, so that every road follows a compass direction without contradiction.
+func GenerateMap(cfg GenerateCfg) (*Map, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	g := newGridGraph(rand.New(rand.NewSource(cfg.Seed)))
+	switch cfg.Kind {
+	case MapGrid:
+		// Holes are cells of the rectangle left empty
+		cells := int(math.Ceil(float64(cfg.Size) / cfg.Density))
+		width := int(math.Ceil(math.Sqrt(float64(cells))))
+		height := (cells + width - 1) / width
+		for _, i := range g.rng.Perm(width * height)[:cfg.Size] {
+			g.cells[GridPoint{i % width, i / width}] = true
+		}
+		g.connectNeighbours(1)
+
+	case MapRandom:
+		g.grow(GridPoint{}, cfg.Size, math.MinInt, math.MaxInt)
+		g.connectNeighbours(cfg.Density)
+
+	case MapTree:
+		g.grow(GridPoint{}, cfg.Size, math.MinInt, math.MaxInt)
+
+	case MapRing:
+		// The border of a rectangle
+		width := (cfg.Size/2 + 2) / 2
+		height := cfg.Size/2 + 2 - width
+		var border []GridPoint
+		for x := 0; x < width; x++ {
+			border = append(border, GridPoint{x, 0})
+		}
+		for y := 1; y < height; y++ {
+			border = append(border, GridPoint{width - 1, y})
+		}
+		for x := width - 2; x >= 0; x-- {
+			border = append(border, GridPoint{x, height - 1})
+		}
+		for y := height - 2; y > 0; y-- {
+			border = append(border, GridPoint{0, y})
+		}
+		for i, p := range border {
+			g.cells[p] = true
+			g.link(p, border[(i+1)%len(border)])
+		}
+
+	case MapContinents:
+		// Continents grow side by side in their own columns, separated by a column of sea,
+		// and each one is linked to the next one by a road along the first row
+		start := 0
+		for i := 0; i < cfg.Continents; i++ {
+			size := cfg.Size / cfg.Continents
+			if i < cfg.Size%cfg.Continents {
+				size++
+			}
+			width := int(math.Ceil(math.Sqrt(float64(size)))) + 1
+
+			g.grow(GridPoint{start + width/2, 0}, size, start, start+width-1)
+			if i > 0 {
+				g.link(lastInRow(g, 0, start-1), firstInRow(g, 0, start))
+			}
+			start += width + 1
+		}
+		g.connectNeighbours(cfg.Density)
+	}
+
+	return g.toMap(), nil
+}
+
+// lastInRow returns the easternmost city of a row up to a column
+func lastInRow(g *gridGraph, y, maxX int) GridPoint {
+	last := GridPoint{math.MinInt, y}
+	for p := range g.cells {
+		if p.Y == y && p.X <= maxX && p.X > last.X {
+			last = p
+		}
+	}
+	return last
+}
+
+// firstInRow returns the westernmost city of a row from a column
+func firstInRow(g *gridGraph, y, minX int) GridPoint {
+	first := GridPoint{math.MaxInt, y}
+	for p := range g.cells {
+		if p.Y == y && p.X >= minX && p.X < first.X {
+			first = p
+		}
+	}
+	return first
+}
+
+// nameGenerator generates unique city names from syllables
+type nameGenerator struct {
+	rng  *rand.Rand
+	used map[string]bool
+}
+
+var (
+	nameSyllables = []string{"al", "bar", "cor", "dun", "el", "fen", "gal", "har", "is", "kor", "lum", "mar", "nor", "or", "pel", "quin", "ros", "sil", "tor", "ul", "vel", "wyn", "zar"}
+	nameSuffixes  = []string{"ia", "ville", "haven", "dale", "hold", "wood", "keep", "moor", "ford", "gate", "fall", "mere", "shire", "ton"}
+)
+
+func newNameGenerator(rng *rand.Rand) *nameGenerator {
+	return &nameGenerator{rng: rng, used: make(map[string]bool)}
+}
+
+// next returns a name that has not been returned yet, numbered once the names run short
+func (n *nameGenerator) next() string {
+	for attempt := 0; ; attempt++ {
+		var name strings.Builder
+		syllables := 1 + n.rng.Intn(2)
+		for i := 0; i < syllables; i++ {
+			name.WriteString(nameSyllables[n.rng.Intn(len(nameSyllables))])
+		}
+		name.WriteString(nameSuffixes[n.rng.Intn(len(nameSuffixes))])
+		candidate := strings.ToUpper(name.String()[:1]) + name.String()[1:]
+		if attempt >= 10 {
+			candidate = fmt.Sprintf("%s%d", candidate, len(n.used))
+		}
+
+		if !n.used[candidate] {
+			n.used[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// DefineGenerateFlags defines the flags for the generate command
+func (a *App) DefineGenerateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("kind", "k", string(MapRandom), "Kind of map: grid, random, tree, ring or continents")
+	cmd.Flags().IntP("size", "n", 20, "Number of cities")
+	cmd.Flags().Float64("density", 0.8, "Share of the grid cells kept (grid) or chance of each extra road (random, continents)")
+	cmd.Flags().Int64("seed", 0, "Seed of the random source, use it to generate the same map again (0 picks a random seed)")
+	cmd.Flags().Int("continents", 3, "Number of continents (continents only)")
+	cmd.Flags().StringP("output", "l", "", "Map output file (stdout if empty)")
+}
+
+// StartGenerate generates a map and writes it in the map file format, see GenerateMap.
+// The seed used is reported so that a map generated with a random seed can be generated again.
+func (a *App) StartGenerate(cmd *cobra.Command, args []string) error {
+	flags := &flagReader{cmd: cmd}
+	cfg := GenerateCfg{
+		Kind:       MapKind(flags.String("kind")),
+		Size:       flags.Int("size"),
+		Density:    flags.Float64("density"),
+		Seed:       flags.Int64("seed"),
+		Continents: flags.Int("continents"),
+	}
+	outputFilename := flags.String("output")
+	if flags.err != nil {
+		return flags.err
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	worldMap, err := GenerateMap(cfg)
+	if err != nil {
+		return err
+	}
+
+	if outputFilename == "" {
+		// the seed goes to stderr, keeping stdout a valid map
+		if err := WriteMap(cmd.OutOrStdout(), worldMap); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Generated a %s map of %d cities with seed %d\n", cfg.Kind, len(worldMap.Cities), cfg.Seed)
+		return nil
+	}
+
+	file, err := os.Create(outputFilename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := WriteMap(w, worldMap); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Generated a %s map of %d cities with seed %d in %s\n", cfg.Kind, len(worldMap.Cities), cfg.Seed, outputFilename)
+	return nil
+}
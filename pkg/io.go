@@ -2,11 +2,16 @@ package simulation
 
 import (
 	"bufio"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	goio "io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
 )
 
 // IOController handles all input and output operations.
@@ -15,8 +20,29 @@ type IOController struct {
 }
 
 // ReadMapFromFile reads the world map from a file.
+// The directions of the roads follow the @directions header of the file if any,
+// or the configured vocabulary otherwise, see ParseDirections.
+// In strict mode the map is validated first and any diagnostic fails the read
+// with a *ValidationError, see ValidateMap.
 func (io *IOController) ReadMapFromFile() error {
-	file, err := os.Open(io.app.Cfg.MapInputFile)
+	filename := io.app.Cfg.MapInputFile
+
+	directions, err := ParseDirections(io.app.Cfg.Directions)
+	if err != nil {
+		return err
+	}
+
+	if io.app.Cfg.Strict {
+		diagnostics, err := ValidateMapFileWithDirections(filename, directions)
+		if err != nil {
+			return err
+		}
+		if len(diagnostics) > 0 {
+			return &ValidationError{Diagnostics: diagnostics}
+		}
+	}
+
+	file, err := os.Open(filename)
 	if err != nil {
 		return fmt.Errorf("error opening file: %w", err)
 	}
@@ -24,32 +50,46 @@ func (io *IOController) ReadMapFromFile() error {
 
 	scanner := bufio.NewScanner(file)
 
-	// Read and create all cities first
+	// Read the directions header and create all cities first
+	// a city without neighbours is valid, it is written as such by WriteMapToFile
+	// blank lines are skipped
+	lineNumber := 0
 	for scanner.Scan() {
-		line := scanner.Text()
-		cityData := strings.Split(line, " ")
+		lineNumber++
+		cityData := strings.Fields(scanner.Text())
+		if len(cityData) < 1 {
+			continue
+		}
 
-		if len(cityData) < 2 {
-			return fmt.Errorf("invalid line: %s", line)
+		if declared, found, err := parseDirectionsHeader(cityData); found {
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+			}
+			if len(io.app.State.WorldMap.Cities) > 0 {
+				return fmt.Errorf("%s:%d: %s must come before the cities", filename, lineNumber, DirectionsHeader)
+			}
+			directions = declared
+			continue
 		}
 
 		cityName := cityData[0]
 
 		city := &City{Name: cityName, Neighbours: make(map[string]*City)}
-		io.app.State.WorldMap.Cities[cityName] = city
+		io.app.State.WorldMap.AddCity(city)
 	}
+	io.app.State.WorldMap.Directions = directions
 
 	// Reset scanner to start again from the beginning
 	file.Seek(0, 0)
 	scanner = bufio.NewScanner(file)
 
 	// Populate neighboring cities
+	lineNumber = 0
 	for scanner.Scan() {
-		line := scanner.Text()
-		cityData := strings.Split(line, " ")
-
-		if len(cityData) < 2 {
-			return fmt.Errorf("invalid line: %s", line)
+		lineNumber++
+		cityData := strings.Fields(scanner.Text())
+		if len(cityData) < 1 || cityData[0] == DirectionsHeader {
+			continue
 		}
 
 		cityName := cityData[0]
@@ -58,19 +98,41 @@ func (io *IOController) ReadMapFromFile() error {
 		city := io.app.State.WorldMap.Cities[cityName]
 
 		for _, neighbourData := range cityNeighbours {
+			if key, value, found := parseAttribute(neighbourData); found {
+				if err := city.SetAttribute(key, value); err != nil {
+					return fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+				}
+				continue
+			}
+
 			neighbour := strings.Split(neighbourData, "=")
 			if len(neighbour) != 2 {
-				return fmt.Errorf("invalid neighbour data: %s", neighbourData)
+				return fmt.Errorf("%s:%d: invalid neighbour data: %s", filename, lineNumber, neighbourData)
 			}
 
-			neighbourName := neighbour[1]
 			direction := neighbour[0]
+			neighbourName, distance, err := parseRoadTarget(neighbour[1])
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+			}
 
 			if destCity, found := io.app.State.WorldMap.Cities[neighbourName]; !found {
-				return fmt.Errorf("neighbour city %s not found for %s", neighbourName, cityName)
+				return fmt.Errorf("%s:%d: neighbour city %s not found for %s", filename, lineNumber, neighbourName, cityName)
 			} else {
 				city.Neighbours[direction] = destCity
-				destCity.Neighbours[OppositeDirection(direction)] = city
+				// a road without distance keeps the one given on the other side, if any
+				if distance > 0 {
+					city.SetDistance(direction, distance)
+				}
+				// directions out of the vocabulary have no back-link, see ValidateMap to report them
+				if opposite := directions.Opposite(direction); opposite != "" {
+					destCity.Neighbours[opposite] = city
+					if distance > 0 {
+						destCity.SetDistance(opposite, distance)
+					}
+				} else {
+					io.app.logger.Logf("%s:%d: unknown direction %s, %s has no road back to %s", filename, lineNumber, direction, neighbourName, cityName)
+				}
 			}
 		}
 	}
@@ -85,7 +147,24 @@ func (io *IOController) ReadMapFromFile() error {
 	return nil
 }
 
+// parseRoadTarget parses the target of a road, a city name optionally followed
+// by the travel time of the road in ticks, as in Avaloria:3.
+// The distance is 0 if not given.
+func parseRoadTarget(target string) (string, int, error) {
+	name, distanceStr, found := strings.Cut(target, ":")
+	if !found {
+		return target, 0, nil
+	}
+
+	distance, err := strconv.Atoi(distanceStr)
+	if err != nil || distance <= 0 {
+		return name, 0, fmt.Errorf("invalid distance %q of the road to %s, expected a positive number of ticks", distanceStr, name)
+	}
+	return name, distance, nil
+}
+
 // WriteMapToFile writes the world map to a file in the same format as the input.
+// See WriteMap
 func (io *IOController) WriteMapToFile() error {
 	file, err := os.Create(io.app.Cfg.MapOutputFile)
 	if err != nil {
@@ -93,15 +172,53 @@ func (io *IOController) WriteMapToFile() error {
 	}
 	defer file.Close()
 
-	for cityName, city := range io.app.State.WorldMap.Cities {
-		line := fmt.Sprintf("%s ", cityName)
+	w := bufio.NewWriter(file)
+	if err := WriteMap(w, io.app.State.WorldMap); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// WriteMap writes the world map in the same format as the input,
+// so that reading it back with ReadMapFromFile yields the same map.
+// The direction vocabulary is declared in a header unless it is the compass one.
+// Cities are written in order of definition, their neighbours in canonical
+// direction order of the vocabulary (see Directions.Sort), separated by a single space.
+// Roads longer than a tick are followed by their distance, as in north=Avaloria:3,
+// and the attributes of the city follow its roads, as in @population=1200.
+func WriteMap(w goio.Writer, worldMap *Map) error {
+	vocabulary := worldMap.Vocabulary()
+	if !vocabulary.IsDefault() {
+		if _, err := fmt.Fprintf(w, "%s %s\n", DirectionsHeader, vocabulary); err != nil {
+			return err
+		}
+	}
+
+	for _, cityName := range worldMap.OrderedCityNames() {
+		city := worldMap.Cities[cityName]
+
+		directions := make([]string, 0, len(city.Neighbours))
 		for direction, neighbour := range city.Neighbours {
-			line += fmt.Sprintf("%s=%s", direction, neighbour.Name)
+			if neighbour != nil {
+				directions = append(directions, direction)
+			}
+		}
+		vocabulary.Sort(directions)
+
+		var line strings.Builder
+		line.WriteString(cityName)
+		for _, direction := range directions {
+			fmt.Fprintf(&line, " %s=%s", direction, city.Neighbours[direction].Name)
+			if distance := city.Distance(direction); distance != 1 {
+				fmt.Fprintf(&line, ":%d", distance)
+			}
 		}
-		line += "\n"
+		for _, attribute := range city.attributeTokens() {
+			line.WriteString(" " + attribute)
+		}
+		line.WriteString("\n")
 
-		_, err := file.WriteString(line)
-		if err != nil {
+		if _, err := goio.WriteString(w, line.String()); err != nil {
 			return err
 		}
 	}
@@ -109,34 +226,123 @@ func (io *IOController) WriteMapToFile() error {
 	return nil
 }
 
-// printResult prints the remaining cities and aliens in separate tables.
-func (io *IOController) PrintResult() {
-	app := io.app
+// Output formats of the simulation result
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+)
+
+// ValidateFormat returns an error if the output format is unknown, an empty format is a table
+func ValidateFormat(format string) error {
+	switch format {
+	case "", FormatTable, FormatJSON, FormatYAML, FormatCSV:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// PrintResult prints the result of the simulation to stdout in the configured format.
+func (io *IOController) PrintResult() error {
+	return io.WriteResult(os.Stdout, io.app.Result(), io.app.Cfg.Format)
+}
+
+// WriteResult writes the result of the simulation in the given format.
+// An empty format defaults to the table format.
+func (io *IOController) WriteResult(w goio.Writer, res Result, format string) error {
+	switch format {
+	case "", FormatTable:
+		io.writeResultTable(w, res)
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		defer enc.Close()
+		return enc.Encode(res)
+	case FormatCSV:
+		return writeResultCSV(w, res)
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
 
-	fmt.Println()
-	fmt.Println("+-------------------------- Simulation Result --------------------------+")
+// writeResultTable prints the remaining cities and aliens in separate tables.
+func (io *IOController) writeResultTable(w goio.Writer, res Result) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "+-------------------------- Simulation Result --------------------------+")
 
-	fmt.Println("Remaining Cities:")
-	printCities(app.State.WorldMap)
+	fmt.Fprintln(w, "Remaining Cities:")
+	printCities(w, res.RemainingCities, res.Vocabulary())
 
-	fmt.Println("\nRemaining Aliens:")
-	printAliens(app.State.Aliens)
+	fmt.Fprintln(w, "\nRemaining Aliens:")
+	printAliens(w, res.Survivors)
 
-	fmt.Println()
-	fmt.Println("Result: ", app.stateCtrl.SimulationResult())
-	fmt.Println("+-----------------------------------------------------------------------+")
-	fmt.Println("The resulting map of the world is saved to:", app.Cfg.MapOutputFile)
+	if len(res.Factions) > 0 {
+		fmt.Fprintln(w, "\nFactions:")
+		printFactions(w, res.Factions)
+	}
+
+	if len(res.Defenders) > 0 || io.app.Cfg.NumDefenders > 0 {
+		fmt.Fprintln(w, "\nRemaining Defenders:")
+		printDefenders(w, res.Defenders)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Result: ", res.Reason)
+	fmt.Fprintln(w, "Ticks: ", res.Ticks)
+	fmt.Fprintln(w, "Seed: ", res.Seed)
+	fmt.Fprintln(w, "Population lost: ", res.PopulationLost)
+	fmt.Fprintln(w, "Defenses overwhelmed: ", res.DefensesOverwhelmed)
+	if res.Waves > 0 || io.app.Cfg.Waves > 0 {
+		fmt.Fprintln(w, "Reinforcement waves: ", res.Waves)
+	}
+	if res.Births > 0 || io.app.Cfg.ReproduceAfter > 0 {
+		fmt.Fprintln(w, "Aliens born: ", res.Births)
+	}
+	fmt.Fprintln(w, "+-----------------------------------------------------------------------+")
+	fmt.Fprintln(w, "The resulting map of the world is saved to:", io.app.Cfg.MapOutputFile)
 }
 
-// printCities prints the remaining cities in a table.
-func printCities(worldMap *Map) {
-	table := tablewriter.NewWriter(os.Stdout)
+// printCities prints the remaining cities in a table, their neighbours in the order of the vocabulary.
+func printCities(w goio.Writer, cities []CityResult, vocabulary *Directions) {
+	table := tablewriter.NewWriter(w)
 	table.SetHeader([]string{"City", "Neighbours"})
 
-	for _, city := range worldMap.Cities {
-		row := []string{city.Name, ""}
-		for direction, neighbour := range city.Neighbours {
-			row[1] += fmt.Sprintf("%s=%s ", direction, neighbour.Name)
+	for _, city := range cities {
+		table.Append([]string{city.Name, city.NeighboursStringWithDirections(vocabulary)})
+	}
+
+	table.Render()
+}
+
+// printAliens prints the remaining aliens in a table,
+// along with their faction if any of them belongs to one.
+func printAliens(w goio.Writer, aliens []AlienResult) {
+	factions := false
+	for _, alien := range aliens {
+		factions = factions || alien.Faction != ""
+	}
+
+	table := tablewriter.NewWriter(w)
+	header := []string{"ID", "Current City", "Moves"}
+	if factions {
+		header = append(header, "Faction")
+	}
+	table.SetHeader(header)
+
+	for _, alien := range aliens {
+		city := alien.City
+		if alien.Arrival > 0 {
+			city = fmt.Sprintf("-> %s (tick %d)", alien.City, alien.Arrival)
+		}
+		row := []string{fmt.Sprintf("%d", alien.ID), city, fmt.Sprintf("%d", alien.Moves)}
+		if factions {
+			row = append(row, alien.Faction)
 		}
 		table.Append(row)
 	}
@@ -144,18 +350,92 @@ func printCities(worldMap *Map) {
 	table.Render()
 }
 
-// printAliens prints the remaining aliens in a table.
-func printAliens(aliens AlienSet) {
-	table := tablewriter.NewWriter(os.Stdout)
+// printFactions prints the survival statistics of the factions in a table.
+func printFactions(w goio.Writer, factions []FactionResult) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Faction", "Landed", "Survivors", "Survival %"})
+
+	for _, faction := range factions {
+		table.Append([]string{faction.Name, strconv.Itoa(faction.Landed), strconv.Itoa(faction.Survivors), fmt.Sprintf("%.1f", faction.Survival)})
+	}
+
+	table.Render()
+}
+
+// printDefenders prints the remaining defenders in a table.
+func printDefenders(w goio.Writer, defenders []DefenderResult) {
+	table := tablewriter.NewWriter(w)
 	table.SetHeader([]string{"ID", "Current City", "Moves"})
 
-	for _, alien := range aliens {
-		table.Append([]string{fmt.Sprintf("%d", alien.ID), fmt.Sprintf("%s", alien.CurrentCity.Name), fmt.Sprintf("%d", alien.Moved)})
+	for _, defender := range defenders {
+		city := defender.City
+		if defender.Arrival > 0 {
+			city = fmt.Sprintf("-> %s (tick %d)", defender.City, defender.Arrival)
+		}
+		table.Append([]string{fmt.Sprintf("%d", defender.ID), city, fmt.Sprintf("%d", defender.Moves)})
 	}
 
 	table.Render()
 }
 
+// writeResultCSV writes the result as csv records of the form: record,name,value,detail
+//
+//	summary,reason,<reason>,
+//	summary,ticks,<ticks>,
+//	summary,seed,<seed>,
+//	summary,population_lost,<population>,
+//	summary,defenses_overwhelmed,<defense levels>,
+//	summary,waves,<waves landed>,       only if reinforcements landed
+//	summary,births,<aliens born>,       only if aliens were born
+//	city,<name>,<neighbours>,
+//	alien,<id>,<city>,<moves>
+//	defender,<id>,<city>,<moves>
+//	faction,<name>,<survivors>,<landed>
+//	destroyed,<name>,<order>,
+func writeResultCSV(w goio.Writer, res Result) error {
+	records := [][]string{
+		{"record", "name", "value", "detail"},
+		{"summary", "reason", string(res.Reason), ""},
+		{"summary", "ticks", strconv.Itoa(res.Ticks), ""},
+		{"summary", "seed", strconv.FormatInt(res.Seed, 10), ""},
+		{"summary", "population_lost", strconv.Itoa(res.PopulationLost), ""},
+		{"summary", "defenses_overwhelmed", strconv.Itoa(res.DefensesOverwhelmed), ""},
+	}
+	if res.Waves > 0 {
+		records = append(records, []string{"summary", "waves", strconv.Itoa(res.Waves), ""})
+	}
+	if res.Births > 0 {
+		records = append(records, []string{"summary", "births", strconv.Itoa(res.Births), ""})
+	}
+
+	vocabulary := res.Vocabulary()
+	for _, city := range res.RemainingCities {
+		records = append(records, []string{"city", city.Name, city.NeighboursStringWithDirections(vocabulary), ""})
+	}
+
+	for _, alien := range res.Survivors {
+		records = append(records, []string{"alien", strconv.Itoa(alien.ID), alien.City, strconv.Itoa(alien.Moves)})
+	}
+
+	for _, defender := range res.Defenders {
+		records = append(records, []string{"defender", strconv.Itoa(defender.ID), defender.City, strconv.Itoa(defender.Moves)})
+	}
+
+	for _, faction := range res.Factions {
+		records = append(records, []string{"faction", faction.Name, strconv.Itoa(faction.Survivors), strconv.Itoa(faction.Landed)})
+	}
+
+	for i, city := range res.DestroyedCities {
+		records = append(records, []string{"destroyed", city, strconv.Itoa(i + 1), ""})
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("error writing csv: %w", err)
+	}
+	return nil
+}
+
 // NewIOController creates a new IOController.
 func NewIOController(app *App) *IOController {
 	return &IOController{app: app}
@@ -0,0 +1,233 @@
+package simulation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Journal is an append-only log of the simulation events.
+// Events are written as one JSON object per line.
+type Journal struct {
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewJournal creates a journal writing to w
+func NewJournal(w io.Writer) *Journal {
+	j := &Journal{enc: json.NewEncoder(w)}
+	if closer, ok := w.(io.Closer); ok {
+		j.closer = closer
+	}
+	return j
+}
+
+// CreateJournal creates a journal file, truncating it if it already exists
+func CreateJournal(filename string) (*Journal, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating journal: %w", err)
+	}
+	return NewJournal(file), nil
+}
+
+// Append appends an event to the journal
+func (j *Journal) Append(e Event) error {
+	return j.enc.Encode(e)
+}
+
+// Close closes the underlying writer if it can be closed
+func (j *Journal) Close() error {
+	if j.closer == nil {
+		return nil
+	}
+	return j.closer.Close()
+}
+
+// ReadJournal reads all the events of a journal
+func ReadJournal(r io.Reader) ([]Event, error) {
+	var events []Event
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid journal entry at line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading journal: %w", err)
+	}
+
+	return events, nil
+}
+
+// ReadJournalFile reads all the events of a journal file
+func ReadJournalFile(filename string) ([]Event, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	defer file.Close()
+
+	return ReadJournal(file)
+}
+
+// Replay rebuilds the state by applying the events in order, stopping after
+// the last event of the given tick. A negative tick replays the whole journal.
+// The termination reason of the result is the recorded one once the end of the simulation is replayed.
+// The world map must already be loaded, see IOController.ReadMapFromFile.
+// It returns the number of events applied.
+func (a *App) Replay(events []Event, until int) (int, error) {
+	applied := 0
+	for _, e := range events {
+		if until >= 0 && e.Tick > until {
+			break
+		}
+
+		// the tick is set first, aliens on the road arrive at a given tick
+		a.State.Tick = e.Tick
+		if err := a.applyEvent(e); err != nil {
+			return applied, fmt.Errorf("event %d (%s): %w", applied, e, err)
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// applyEvent applies a single event to the state
+func (a *App) applyEvent(e Event) error {
+	switch e.Type {
+	case EventSimulationEnded:
+		a.reason = TerminationReason(e.Reason)
+		return nil
+	case EventSimulationStarted, EventRoadEncounter, EventTickEnded:
+		return nil
+	case EventAlienRepelled, EventCityDefended:
+		// the aliens and defenders falling are destroyed by their own events
+		return nil
+	case EventAlienLanded:
+		city, found := a.State.WorldMap.Cities[e.City]
+		if !found {
+			return fmt.Errorf("city %s does not exist in world map", e.City)
+		}
+		alien, found := a.State.Aliens[e.Alien]
+		if !found {
+			alien = &Alien{ID: e.Alien}
+		}
+		alien.CurrentCity = city
+		alien.Faction = e.Faction
+		a.stateCtrl.addAlien(alien)
+		return nil
+	case EventWaveLanded:
+		a.State.WavesLanded++
+		return nil
+	case EventAlienBorn:
+		parent, found := a.State.Aliens[e.Parent]
+		if !found {
+			return fmt.Errorf("parent alien %d does not exist in the world", e.Parent)
+		}
+		if _, found := a.State.Aliens[e.Alien]; found {
+			return fmt.Errorf("alien %d already exists in the world", e.Alien)
+		}
+		city, found := a.State.WorldMap.Cities[e.City]
+		if !found {
+			return fmt.Errorf("city %s does not exist in world map", e.City)
+		}
+		a.stateCtrl.giveBirth(parent, &Alien{ID: e.Alien, CurrentCity: city, Faction: e.Faction})
+		return nil
+	case EventAlienMoved:
+		alien, found := a.State.Aliens[e.Alien]
+		if !found {
+			return fmt.Errorf("alien %d does not exist in the world", e.Alien)
+		}
+		city, found := a.State.WorldMap.Cities[e.City]
+		if !found {
+			return fmt.Errorf("city %s does not exist in world map", e.City)
+		}
+		a.stateCtrl.moveAlien(alien, city)
+		return nil
+	case EventAlienArrived:
+		alien, found := a.State.Aliens[e.Alien]
+		if !found {
+			return fmt.Errorf("alien %d does not exist in the world", e.Alien)
+		}
+		if !alien.InTransit() || alien.CurrentCity.Name != e.City {
+			return fmt.Errorf("alien %d is not on the road to %s", e.Alien, e.City)
+		}
+		a.stateCtrl.arriveAlien(alien)
+		return nil
+	case EventCityDamaged:
+		if _, found := a.State.WorldMap.Cities[e.City]; !found {
+			return fmt.Errorf("city %s does not exist in world map", e.City)
+		}
+		if a.State.CityDamage == nil {
+			a.State.CityDamage = make(map[string]int)
+		}
+		a.State.CityDamage[e.City]++
+		return nil
+	case EventCityDestroyed:
+		return a.stateCtrl.DestroyCity(e.City)
+	case EventAlienDestroyed:
+		// aliens are already destroyed along with their city
+		if _, found := a.State.Aliens[e.Alien]; !found {
+			return nil
+		}
+		return a.stateCtrl.DestroyAlien(e.Alien)
+	case EventDefenderLanded:
+		city, found := a.State.WorldMap.Cities[e.City]
+		if !found {
+			return fmt.Errorf("city %s does not exist in world map", e.City)
+		}
+		if a.State.Defenders == nil {
+			a.State.Defenders = make(DefenderSet)
+		}
+		defender, found := a.State.Defenders[e.Defender]
+		if !found {
+			defender = &Defender{ID: e.Defender}
+			a.State.Defenders[e.Defender] = defender
+		}
+		a.stateCtrl.landDefender(defender, city)
+		return nil
+	case EventDefenderMoved:
+		defender, found := a.State.Defenders[e.Defender]
+		if !found {
+			return fmt.Errorf("defender %d does not exist in the world", e.Defender)
+		}
+		city, found := a.State.WorldMap.Cities[e.City]
+		if !found {
+			return fmt.Errorf("city %s does not exist in world map", e.City)
+		}
+		a.stateCtrl.moveDefender(defender, city)
+		return nil
+	case EventDefenderArrived:
+		defender, found := a.State.Defenders[e.Defender]
+		if !found {
+			return fmt.Errorf("defender %d does not exist in the world", e.Defender)
+		}
+		if !defender.InTransit() || defender.CurrentCity.Name != e.City {
+			return fmt.Errorf("defender %d is not on the road to %s", e.Defender, e.City)
+		}
+		a.stateCtrl.landDefender(defender, defender.CurrentCity)
+		return nil
+	case EventDefenderDestroyed:
+		// defenders are already destroyed along with their city
+		if _, found := a.State.Defenders[e.Defender]; !found {
+			return nil
+		}
+		return a.stateCtrl.DestroyDefender(e.Defender)
+	default:
+		return fmt.Errorf("unknown event type %s", e.Type)
+	}
+}
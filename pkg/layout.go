@@ -0,0 +1,285 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GridPoint is a position on the grid of a map layout,
+// X grows eastwards and Y southwards
+type GridPoint struct {
+	X, Y int
+}
+
+// Layout assigns grid positions to the cities of a map so that every
+// north/south road is vertical and every east/west road is horizontal,
+// see ComputeLayout
+type Layout struct {
+	Positions map[string]GridPoint
+	Width     int // number of columns
+	Height    int // number of rows
+}
+
+// At returns the city at a grid position, if any
+func (l Layout) At(p GridPoint) (string, bool) {
+	for name, position := range l.Positions {
+		if position == p {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Grid returns the city names by row and column, empty where there is no city
+func (l Layout) Grid() [][]string {
+	grid := make([][]string, l.Height)
+	for y := range grid {
+		grid[y] = make([]string, l.Width)
+	}
+	for name, p := range l.Positions {
+		grid[p.Y][p.X] = name
+	}
+	return grid
+}
+
+// LayoutError is returned when the roads of a map cannot be drawn on a grid
+type LayoutError struct {
+	Problems []string
+}
+
+// Error lists the problems, one per line
+func (e *LayoutError) Error() string {
+	return fmt.Sprintf("map cannot be laid out on a grid:\n%s", strings.Join(e.Problems, "\n"))
+}
+
+// unionFind groups the cities sharing a coordinate
+type unionFind map[string]string
+
+func (u unionFind) find(name string) string {
+	for u[name] != name {
+		u[name] = u[u[name]]
+		name = u[name]
+	}
+	return name
+}
+
+func (u unionFind) union(a, b string) {
+	u[u.find(a)] = u.find(b)
+}
+
+// axisOrder is the ordering constraints between the classes of cities sharing a coordinate
+type axisOrder struct {
+	classes unionFind
+	after   map[string]map[string]bool // class -> classes with a strictly greater coordinate
+}
+
+func newAxisOrder(names []string) *axisOrder {
+	o := &axisOrder{classes: make(unionFind, len(names)), after: make(map[string]map[string]bool)}
+	for _, name := range names {
+		o.classes[name] = name
+	}
+	return o
+}
+
+// sorted returns the classes in topological order, or the classes on a cycle.
+// rank breaks the ties so that the order only depends on the map.
+func (o *axisOrder) sorted(rank map[string]int) ([]string, []string) {
+	indegree := make(map[string]int)
+	roots := make(map[string]bool)
+	for name := range o.classes {
+		roots[o.classes.find(name)] = true
+	}
+	for class := range roots {
+		for next := range o.after[class] {
+			indegree[next]++
+		}
+	}
+
+	var ready, order []string
+	for class := range roots {
+		if indegree[class] == 0 {
+			ready = append(ready, class)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return rank[ready[i]] < rank[ready[j]] })
+		class := ready[0]
+		ready = ready[1:]
+		order = append(order, class)
+		for next := range o.after[class] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	if len(order) == len(roots) {
+		return order, nil
+	}
+
+	var cycle []string
+	for class := range roots {
+		if indegree[class] > 0 {
+			cycle = append(cycle, class)
+		}
+	}
+	return nil, cycle
+}
+
+// ComputeLayout assigns grid positions to the cities of a map from its compass roads:
+// cities linked north/south share a column and cities linked east/west share a row.
+// Roads may span several cells, columns are packed so that no two cities share a cell.
+// Roads in other directions are ignored.
+// It returns a *LayoutError if the roads contradict each other, like a city east of itself.
+func ComputeLayout(m *Map) (Layout, error) {
+	names := m.OrderedCityNames()
+	rank := make(map[string]int, len(names))
+	for i, name := range names {
+		rank[name] = i
+	}
+
+	columns, rows := newAxisOrder(names), newAxisOrder(names)
+	type constraint struct {
+		order       *axisOrder
+		first, then string
+	}
+	var constraints []constraint
+	for _, name := range names {
+		city := m.Cities[name]
+		for direction, neighbour := range city.Neighbours {
+			if neighbour == nil {
+				continue
+			}
+			switch direction {
+			case "north":
+				columns.classes.union(name, neighbour.Name)
+				constraints = append(constraints, constraint{rows, neighbour.Name, name})
+			case "south":
+				columns.classes.union(name, neighbour.Name)
+				constraints = append(constraints, constraint{rows, name, neighbour.Name})
+			case "east":
+				rows.classes.union(name, neighbour.Name)
+				constraints = append(constraints, constraint{columns, name, neighbour.Name})
+			case "west":
+				rows.classes.union(name, neighbour.Name)
+				constraints = append(constraints, constraint{columns, neighbour.Name, name})
+			}
+		}
+	}
+
+	var problems []string
+	for _, c := range constraints {
+		first, then := c.order.classes.find(c.first), c.order.classes.find(c.then)
+		if first == then {
+			axis := "column"
+			if c.order == rows {
+				axis = "row"
+			}
+			problems = append(problems, fmt.Sprintf("%s and %s would be in the same %s and apart along it", c.first, c.then, axis))
+			continue
+		}
+		if c.order.after[first] == nil {
+			c.order.after[first] = make(map[string]bool)
+		}
+		c.order.after[first][then] = true
+	}
+
+	// Two cities in the same column and the same row would share a cell
+	cells := make(map[[2]string]string)
+	for _, name := range names {
+		cell := [2]string{columns.classes.find(name), rows.classes.find(name)}
+		if other, found := cells[cell]; found {
+			problems = append(problems, fmt.Sprintf("%s and %s would share the same cell", other, name))
+			continue
+		}
+		cells[cell] = name
+	}
+
+	// class ranks follow their first city
+	classRank := func(o *axisOrder) map[string]int {
+		r := make(map[string]int)
+		for _, name := range names {
+			class := o.classes.find(name)
+			if _, found := r[class]; !found {
+				r[class] = rank[name]
+			}
+		}
+		return r
+	}
+
+	rowOrder, cycle := rows.sorted(classRank(rows))
+	if cycle != nil {
+		problems = append(problems, fmt.Sprintf("the rows of %s are both north and south of each other", strings.Join(sortedByRank(cycle, rank), ", ")))
+	}
+	columnOrder, cycle := columns.sorted(classRank(columns))
+	if cycle != nil {
+		problems = append(problems, fmt.Sprintf("the columns of %s are both east and west of each other", strings.Join(sortedByRank(cycle, rank), ", ")))
+	}
+
+	if len(problems) > 0 {
+		return Layout{}, &LayoutError{Problems: problems}
+	}
+
+	// Rows first, each one right below the rows north of it
+	rowY := make(map[string]int)
+	for _, class := range rowOrder {
+		for next := range rows.after[class] {
+			if rowY[next] < rowY[class]+1 {
+				rowY[next] = rowY[class] + 1
+			}
+		}
+	}
+
+	// Then columns, each one in the first free column east of the columns west of it
+	members := make(map[string][]string)
+	for _, name := range names {
+		class := columns.classes.find(name)
+		members[class] = append(members[class], name)
+	}
+
+	layout := Layout{Positions: make(map[string]GridPoint, len(names))}
+	occupied := make(map[GridPoint]bool)
+	minX := make(map[string]int)
+	for _, class := range columnOrder {
+		free := func(x int) bool {
+			for _, name := range members[class] {
+				if occupied[GridPoint{x, rowY[rows.classes.find(name)]}] {
+					return false
+				}
+			}
+			return true
+		}
+		x := minX[class]
+		for !free(x) {
+			x++
+		}
+
+		for _, name := range members[class] {
+			p := GridPoint{x, rowY[rows.classes.find(name)]}
+			occupied[p] = true
+			layout.Positions[name] = p
+			if p.X+1 > layout.Width {
+				layout.Width = p.X + 1
+			}
+			if p.Y+1 > layout.Height {
+				layout.Height = p.Y + 1
+			}
+		}
+		for next := range columns.after[class] {
+			if minX[next] < x+1 {
+				minX[next] = x + 1
+			}
+		}
+	}
+
+	return layout, nil
+}
+
+// sortedByRank returns the names in map order
+func sortedByRank(names []string, rank map[string]int) []string {
+	sorted := append([]string(nil), names...)
+	sort.Slice(sorted, func(i, j int) bool { return rank[sorted[i]] < rank[sorted[j]] })
+	return sorted
+}
@@ -36,3 +36,8 @@ func NewFileLogger(filename string) (*Logger, error) {
 func NewStdoutLogger() *Logger {
 	return NewLogger(os.Stdout)
 }
+
+// NewDiscardLogger creates a logger that discards every message.
+func NewDiscardLogger() *Logger {
+	return NewLogger(io.Discard)
+}
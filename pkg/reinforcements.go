@@ -0,0 +1,99 @@
+package simulation
+
+// HasPendingWaves returns true if reinforcement waves are still to land:
+// fewer waves than configured have landed and a city remains to land them in.
+func (sc *StateController) HasPendingWaves() bool {
+	cfg := sc.app.Cfg
+	if cfg == nil || sc.app.State.WavesLanded >= cfg.Waves {
+		return false
+	}
+	return len(sc.app.waveCities()) > 0
+}
+
+// waveCities returns the cities the waves may land in: the remaining configured
+// cities in their configured order, or every remaining city sorted by name if none is configured
+func (a *App) waveCities() []*City {
+	var cities []*City
+	if len(a.Cfg.WaveCities) == 0 {
+		for _, name := range a.State.WorldMap.CityNames() {
+			cities = append(cities, a.State.WorldMap.Cities[name])
+		}
+		return cities
+	}
+
+	for _, name := range a.Cfg.WaveCities {
+		if city, found := a.State.WorldMap.Cities[name]; found {
+			cities = append(cities, city)
+		}
+	}
+	return cities
+}
+
+// LandWave lands a wave of reinforcements every Cfg.WaveEvery ticks as long as waves are pending.
+// The aliens of a wave get fresh IDs, are split into the configured factions
+// and land in the remaining wave cities in turn, or in random cities if none is configured.
+func (a *App) LandWave() {
+	if !a.stateCtrl.HasPendingWaves() || a.State.Tick%a.Cfg.WaveEvery != 0 {
+		return
+	}
+
+	shares, _ := ParseFactions(a.Cfg.Factions)
+	factions := AssignFactions(shares, a.Cfg.WaveSize)
+	cities := a.waveCities()
+	ids := make([]int, 0, a.Cfg.WaveSize)
+	for i := 0; i < a.Cfg.WaveSize; i++ {
+		var city *City
+		if len(a.Cfg.WaveCities) == 0 {
+			city = a.getRandomCity()
+		} else {
+			city = cities[i%len(cities)]
+		}
+
+		alien := &Alien{ID: a.State.NextAlienID, CurrentCity: city, Faction: factions[i]}
+		a.stateCtrl.addAlien(alien)
+		a.stateCtrl.emit(Event{Type: EventAlienLanded, Alien: alien.ID, City: city.Name, Faction: alien.Faction})
+		ids = append(ids, alien.ID)
+	}
+
+	a.stateCtrl.emit(Event{Type: EventWaveLanded, Aliens: ids})
+	a.State.WavesLanded++
+	a.logger.Logf("Wave %d of %d aliens has landed.", a.State.WavesLanded, a.Cfg.WaveSize)
+}
+
+// ReproduceAliens makes every alien in a city having moved Cfg.ReproduceAfter times
+// give birth to a single offspring in its city, in ID order. Offspring start afresh
+// with the faction and strategy of their parent, and reproduce in turn.
+func (sc *StateController) ReproduceAliens() {
+	after := sc.app.Cfg.ReproduceAfter
+	if after == 0 {
+		return
+	}
+
+	for _, id := range sc.app.State.Aliens.IDs() {
+		parent := sc.app.State.Aliens[id]
+		if parent.Reproduced || parent.InTransit() || parent.Moved < after {
+			continue
+		}
+
+		offspring := &Alien{ID: sc.app.State.NextAlienID, CurrentCity: parent.CurrentCity, Strategy: parent.Strategy, Faction: parent.Faction}
+		sc.emit(Event{Type: EventAlienBorn, Alien: offspring.ID, City: parent.CurrentCity.Name, Parent: parent.ID, Faction: offspring.Faction})
+		sc.giveBirth(parent, offspring)
+	}
+}
+
+// giveBirth adds the offspring of an alien to its city
+func (sc *StateController) giveBirth(parent, offspring *Alien) {
+	parent.Reproduced = true
+	sc.addAlien(offspring)
+	sc.app.State.Births++
+}
+
+// addAlien adds a landed or born alien to the state and to its city
+func (sc *StateController) addAlien(alien *Alien) {
+	sc.app.State.Aliens[alien.ID] = alien
+	if alien.ID >= sc.app.State.NextAlienID {
+		sc.app.State.NextAlienID = alien.ID + 1
+	}
+	sc.countLanding(alien)
+	sc.placeAlien(alien)
+}
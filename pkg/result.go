@@ -0,0 +1,177 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TerminationReason describes why a simulation run ended
+type TerminationReason string
+
+const (
+	ReasonWorldDestroyed   TerminationReason = "The world has been destroyed"
+	ReasonAliensDestroyed  TerminationReason = "All aliens have been destroyed"
+	ReasonMovementLimit    TerminationReason = "Alien movement limit reached"
+	ReasonAliensTrapped    TerminationReason = "All remaining aliens are trapped"
+	ReasonStopped          TerminationReason = "The simulation has been stopped"
+	ReasonCancelled        TerminationReason = "The simulation has been cancelled"
+	ReasonDeadlineExceeded TerminationReason = "The simulation deadline has been exceeded"
+	ReasonInProgress       TerminationReason = "The simulation is in progress" // replayed up to a tick before its end
+	ReasonUnknown          TerminationReason = "Unknown"
+)
+
+// AlienResult describes an alien that survived the simulation
+type AlienResult struct {
+	ID    int    `json:"id" yaml:"id"`
+	City  string `json:"city" yaml:"city"`
+	Moves int    `json:"moves" yaml:"moves"`
+
+	Arrival int    `json:"arrival,omitempty" yaml:"arrival,omitempty"` // tick at which it reaches City while on the road
+	Faction string `json:"faction,omitempty" yaml:"faction,omitempty"`
+}
+
+// DefenderResult describes a defender that survived the simulation
+type DefenderResult struct {
+	ID    int    `json:"id" yaml:"id"`
+	City  string `json:"city" yaml:"city"`
+	Moves int    `json:"moves" yaml:"moves"`
+
+	Arrival int `json:"arrival,omitempty" yaml:"arrival,omitempty"` // tick at which it reaches City while on the road
+}
+
+// CityResult describes a city that survived the simulation
+type CityResult struct {
+	Name       string            `json:"name" yaml:"name"`
+	Neighbours map[string]string `json:"neighbours" yaml:"neighbours"`                   // direction -> neighbour name
+	Distances  map[string]int    `json:"distances,omitempty" yaml:"distances,omitempty"` // direction -> travel time of roads longer than a tick
+}
+
+// NeighboursString returns the neighbours in the map file format, in compass direction order
+func (c CityResult) NeighboursString() string {
+	return c.NeighboursStringWithDirections(DefaultDirections())
+}
+
+// NeighboursStringWithDirections returns the neighbours in the map file format,
+// in the direction order of the vocabulary, see Result.Vocabulary
+func (c CityResult) NeighboursStringWithDirections(vocabulary *Directions) string {
+	directions := make([]string, 0, len(c.Neighbours))
+	for direction := range c.Neighbours {
+		directions = append(directions, direction)
+	}
+	vocabulary.Sort(directions)
+
+	links := make([]string, 0, len(directions))
+	for _, direction := range directions {
+		link := fmt.Sprintf("%s=%s", direction, c.Neighbours[direction])
+		if distance, found := c.Distances[direction]; found {
+			link += fmt.Sprintf(":%d", distance)
+		}
+		links = append(links, link)
+	}
+	return strings.Join(links, " ")
+}
+
+// Result is the outcome of a simulation run
+type Result struct {
+	Reason          TerminationReason `json:"reason" yaml:"reason"`
+	Ticks           int               `json:"ticks" yaml:"ticks"`
+	Seed            int64             `json:"seed" yaml:"seed"`
+	Directions      string            `json:"directions,omitempty" yaml:"directions,omitempty"` // Direction vocabulary of the map, empty for the compass
+	RemainingCities []CityResult      `json:"remaining_cities" yaml:"remaining_cities"`         // Remaining cities sorted by name
+	Survivors       []AlienResult     `json:"survivors" yaml:"survivors"`                       // Surviving aliens sorted by ID
+	Defenders       []DefenderResult  `json:"defenders,omitempty" yaml:"defenders,omitempty"`   // Surviving defenders sorted by ID
+	Factions        []FactionResult   `json:"factions,omitempty" yaml:"factions,omitempty"`     // Survival of the factions sorted by name
+	DestroyedCities []string          `json:"destroyed_cities" yaml:"destroyed_cities"`         // Destroyed cities in order of destruction
+
+	PopulationLost      int `json:"population_lost" yaml:"population_lost"`           // Population of the destroyed cities
+	DefensesOverwhelmed int `json:"defenses_overwhelmed" yaml:"defenses_overwhelmed"` // Defense levels of the destroyed cities
+
+	Waves  int `json:"waves,omitempty" yaml:"waves,omitempty"`   // Reinforcement waves landed
+	Births int `json:"births,omitempty" yaml:"births,omitempty"` // Aliens born
+}
+
+// Vocabulary returns the direction vocabulary of the map, the compass if it is empty or invalid
+func (r Result) Vocabulary() *Directions {
+	directions, err := ParseDirections(r.Directions)
+	if err != nil {
+		return DefaultDirections()
+	}
+	return directions
+}
+
+// Result returns the result of the simulation from the current state.
+// The termination reason is the one of the last run, or deduced from the state
+// if the simulation has not been run.
+func (a *App) Result() Result {
+	reason := a.reason
+	if reason == "" {
+		reason = a.stateCtrl.TerminationReason()
+	}
+
+	res := Result{
+		Reason:          reason,
+		Ticks:           a.State.Tick,
+		Seed:            a.Cfg.Seed,
+		RemainingCities: make([]CityResult, 0, len(a.State.WorldMap.Cities)),
+		Survivors:       make([]AlienResult, 0, len(a.State.Aliens)),
+		DestroyedCities: append([]string{}, a.State.DestroyedCities...),
+
+		PopulationLost:      a.State.PopulationLost,
+		DefensesOverwhelmed: a.State.DefensesOverwhelmed,
+		Factions:            a.factionResults(),
+
+		Waves:  a.State.WavesLanded,
+		Births: a.State.Births,
+	}
+	if vocabulary := a.State.WorldMap.Vocabulary(); !vocabulary.IsDefault() {
+		res.Directions = vocabulary.String()
+	}
+
+	for _, name := range a.State.WorldMap.CityNames() {
+		city := a.State.WorldMap.Cities[name]
+		remaining := CityResult{Name: name, Neighbours: make(map[string]string, len(city.Neighbours))}
+		for direction, neighbour := range city.Neighbours {
+			if neighbour == nil {
+				continue
+			}
+			remaining.Neighbours[direction] = neighbour.Name
+			if distance := city.Distance(direction); distance != 1 {
+				if remaining.Distances == nil {
+					remaining.Distances = make(map[string]int)
+				}
+				remaining.Distances[direction] = distance
+			}
+		}
+		res.RemainingCities = append(res.RemainingCities, remaining)
+	}
+
+	for _, id := range a.State.Aliens.IDs() {
+		alien := a.State.Aliens[id]
+		survivor := AlienResult{ID: alien.ID, Moves: alien.Moved, Arrival: alien.Arrival, Faction: alien.Faction}
+		if alien.CurrentCity != nil {
+			survivor.City = alien.CurrentCity.Name
+		}
+		res.Survivors = append(res.Survivors, survivor)
+	}
+
+	for _, id := range a.State.Defenders.IDs() {
+		defender := a.State.Defenders[id]
+		res.Defenders = append(res.Defenders, DefenderResult{
+			ID:      id,
+			City:    defender.CurrentCity.Name,
+			Moves:   defender.Moved,
+			Arrival: defender.Arrival,
+		})
+	}
+
+	return res
+}
+
+// contextReason returns the termination reason matching a context error
+func contextReason(err error) TerminationReason {
+	if err == context.DeadlineExceeded {
+		return ReasonDeadlineExceeded
+	}
+	return ReasonCancelled
+}
@@ -0,0 +1,175 @@
+package simulation
+
+import (
+	"fmt"
+)
+
+// CollisionMode decides what happens to the aliens meeting in a city
+type CollisionMode string
+
+const (
+	// CollisionDestroy destroys every alien of the encounter, see CollisionRules.SurvivalChance
+	CollisionDestroy CollisionMode = "destroy"
+	// CollisionFight lets a single random alien of the encounter survive
+	CollisionFight CollisionMode = "fight"
+)
+
+// CollisionRules are the rules applied when aliens meet in a city.
+// The zero value applies the original rules: two aliens meeting in a city
+// destroy it along with themselves.
+// Aliens belonging to factions do not follow the mode and survival chance:
+// allies cooperate while enemies battle, see ParseFactions.
+type CollisionRules struct {
+	Threshold      int           // Number of aliens in a city triggering an encounter, 2 if zero
+	SurvivalChance float64       // Chance of each alien surviving an encounter in destroy mode
+	Mode           CollisionMode // destroy (default) or fight
+	CityHitPoints  int           // Number of encounters a city absorbs before falling, 1 if zero, see City.DefenseLevel
+}
+
+// WithDefaults returns the rules with the defaults of unset fields applied
+func (r CollisionRules) WithDefaults() CollisionRules {
+	if r.Threshold == 0 {
+		r.Threshold = 2
+	}
+	if r.Mode == "" {
+		r.Mode = CollisionDestroy
+	}
+	if r.CityHitPoints == 0 {
+		r.CityHitPoints = 1
+	}
+	return r
+}
+
+// Validate returns an error if the rules cannot be applied
+func (r CollisionRules) Validate() error {
+	if r.Threshold < 0 || r.Threshold == 1 {
+		return fmt.Errorf("collision threshold must be at least 2, got %d", r.Threshold)
+	}
+	if r.SurvivalChance < 0 || r.SurvivalChance > 1 {
+		return fmt.Errorf("survival chance must be in [0, 1], got %v", r.SurvivalChance)
+	}
+	switch r.Mode {
+	case "", CollisionDestroy, CollisionFight:
+	default:
+		return fmt.Errorf("unknown collision mode %q", r.Mode)
+	}
+	if r.CityHitPoints < 0 {
+		return fmt.Errorf("city hit points must be positive, got %d", r.CityHitPoints)
+	}
+	return nil
+}
+
+// Rules returns the collision rules of the simulation with defaults applied
+func (sc *StateController) Rules() CollisionRules {
+	return sc.app.Cfg.Rules.WithDefaults()
+}
+
+// ResolveCollisions resolves the encounters of every city holding
+// at least the threshold number of aliens, in alphabetical order of the cities.
+// The defenders of the other cities repel their aliens first, see Defender.
+// Cities only crowded by aliens fleeing an encounter are resolved on the next call.
+func (sc *StateController) ResolveCollisions() error {
+	rules := sc.Rules()
+
+	var crowded []*City
+	for _, name := range sc.app.State.WorldMap.CityNames() {
+		city := sc.app.State.WorldMap.Cities[name]
+		if len(sc.app.State.AlienLocations[city]) >= rules.Threshold {
+			crowded = append(crowded, city)
+		} else if err := sc.repelAliens(city); err != nil {
+			return err
+		}
+	}
+
+	for _, city := range crowded {
+		if err := sc.resolveEncounter(city, rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveEncounter resolves an encounter in a city:
+// the losers are destroyed and the city takes a hit,
+// or a defender of the city falls holding it instead.
+// If the city falls, the survivors flee to a random neighbour,
+// or perish with the city if there is none.
+func (sc *StateController) resolveEncounter(city *City, rules CollisionRules) error {
+	ids := sc.app.State.AlienLocations[city].IDs()
+
+	survives, factions := sc.factionSurvivors(ids)
+	switch {
+	case factions:
+		// allies cooperate, enemies battle
+	case rules.Mode == CollisionFight:
+		survives = map[int]bool{ids[sc.app.rng.Intn(len(ids))]: true}
+	default:
+		survives = make(map[int]bool, len(ids))
+		// draw only if needed so that the default rules do not consume the random source
+		if rules.SurvivalChance > 0 {
+			for _, id := range ids {
+				survives[id] = sc.app.rng.Float64() < rules.SurvivalChance
+			}
+		}
+	}
+
+	if defenders := sc.app.State.DefenderLocations[city]; len(defenders) > 0 {
+		defender := defenders.IDs()[0]
+		sc.emit(Event{Type: EventCityDefended, City: city.Name, Aliens: ids, Defender: defender})
+		if err := sc.DestroyDefender(defender); err != nil {
+			return err
+		}
+		if sc.printer != nil {
+			sc.printer.Log(fmt.Sprintf("City %s has been held by defender %d against aliens %v", city.Name, defender, ids))
+		}
+		return sc.destroyLosers(ids, survives)
+	}
+
+	if sc.app.State.CityDamage == nil {
+		sc.app.State.CityDamage = make(map[string]int)
+	}
+	sc.app.State.CityDamage[city.Name]++
+
+	// defended cities absorb more encounters
+	hitPoints := rules.CityHitPoints + city.DefenseLevel()
+	if damage := sc.app.State.CityDamage[city.Name]; damage < hitPoints {
+		sc.emit(Event{Type: EventCityDamaged, City: city.Name, Aliens: ids})
+		msg := fmt.Sprintf("City %s has been attacked by aliens %v (%d/%d hits)", city.Name, ids, damage, hitPoints)
+		if err := sc.destroyLosers(ids, survives); err != nil {
+			return err
+		}
+		if sc.printer != nil {
+			sc.printer.Log(msg)
+		}
+		return nil
+	}
+
+	for _, id := range ids {
+		if !survives[id] {
+			continue
+		}
+
+		alien := sc.app.State.Aliens[id]
+		neighbour, err := GetRandomNeighbor(sc.app.rng, city)
+		if err != nil {
+			// nowhere to flee
+			continue
+		}
+		sc.emit(Event{Type: EventAlienMoved, Alien: id, From: city.Name, City: neighbour.Name})
+		sc.moveAlien(alien, neighbour)
+	}
+
+	return sc.DestroyCity(city.Name)
+}
+
+// destroyLosers destroys the aliens of an encounter that did not survive it
+func (sc *StateController) destroyLosers(ids []int, survives map[int]bool) error {
+	for _, id := range ids {
+		if !survives[id] {
+			if err := sc.DestroyAlien(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+)
+
+// snapshotVersion is the version of the snapshot format written by Snapshot
+const snapshotVersion = 1
+
+// Snapshot is the serialized state of a simulation between two ticks, see App.Snapshot
+type Snapshot struct {
+	Version         int                `json:"version"`
+	Seed            int64              `json:"seed"`
+	Draws           uint64             `json:"draws"` // number of values drawn from the random source
+	Tick            int                `json:"tick"`
+	Directions      string             `json:"directions,omitempty"` // direction vocabulary of the map, see ParseDirections
+	Cities          []CitySnapshot     `json:"cities"`               // in order of definition
+	Aliens          []AlienSnapshot    `json:"aliens"`               // in ID order
+	Defenders       []DefenderSnapshot `json:"defenders,omitempty"`  // in ID order
+	DestroyedCities []string           `json:"destroyed_cities,omitempty"`
+	CityDamage      map[string]int     `json:"city_damage,omitempty"`
+
+	PopulationLost      int `json:"population_lost,omitempty"`
+	DefensesOverwhelmed int `json:"defenses_overwhelmed,omitempty"`
+
+	FactionLanded map[string]int `json:"faction_landed,omitempty"`
+
+	NextAlienID int `json:"next_alien_id,omitempty"` // the highest alien ID plus one if missing
+	WavesLanded int `json:"waves_landed,omitempty"`
+	Births      int `json:"births,omitempty"`
+}
+
+// CitySnapshot is a remaining city and its roads
+type CitySnapshot struct {
+	Name       string            `json:"name"`
+	Neighbours map[string]string `json:"neighbours,omitempty"` // direction to neighbour name
+	Distances  map[string]int    `json:"distances,omitempty"`  // direction to travel time, see City.Distances
+	Population int               `json:"population,omitempty"`
+	Defense    int               `json:"defense,omitempty"`
+	Terrain    Terrain           `json:"terrain,omitempty"`
+}
+
+// AlienSnapshot is a remaining alien
+type AlienSnapshot struct {
+	ID         int    `json:"id"`
+	City       string `json:"city"`
+	Moved      int    `json:"moved"`
+	Strategy   string `json:"strategy,omitempty"` // spec of its own strategy, see ParseStrategy
+	Arrival    int    `json:"arrival,omitempty"`  // tick at which it reaches its city while on the road
+	Faction    string `json:"faction,omitempty"`
+	Reproduced bool   `json:"reproduced,omitempty"`
+}
+
+// DefenderSnapshot is a remaining defender
+type DefenderSnapshot struct {
+	ID      int    `json:"id"`
+	City    string `json:"city"`
+	Moved   int    `json:"moved"`
+	Arrival int    `json:"arrival,omitempty"` // tick at which it reaches its city while on the road
+}
+
+// countingSource is a seeded random source counting the values drawn from it
+// so that its state can be restored by drawing as many values from the same seed
+type countingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// skip draws n values from the source
+func (s *countingSource) skip(n uint64) {
+	for s.draws < n {
+		s.Int63()
+	}
+}
+
+// Snapshot writes the state of the simulation as JSON: the remaining cities and roads,
+// the aliens with their positions, move counters and strategies, the defenders,
+// the random source state and the tick.
+// It must not be called while Run is ticking, see RequestSnapshot.
+func (a *App) Snapshot(w io.Writer) error {
+	snapshot := Snapshot{
+		Version:         snapshotVersion,
+		Seed:            a.rngSrc.seed,
+		Draws:           a.rngSrc.draws,
+		Tick:            a.State.Tick,
+		Directions:      a.State.WorldMap.Vocabulary().String(),
+		Cities:          make([]CitySnapshot, 0, len(a.State.WorldMap.Cities)),
+		Aliens:          make([]AlienSnapshot, 0, len(a.State.Aliens)),
+		DestroyedCities: a.State.DestroyedCities,
+		CityDamage:      a.State.CityDamage,
+
+		PopulationLost:      a.State.PopulationLost,
+		DefensesOverwhelmed: a.State.DefensesOverwhelmed,
+		FactionLanded:       a.State.FactionLanded,
+
+		NextAlienID: a.State.NextAlienID,
+		WavesLanded: a.State.WavesLanded,
+		Births:      a.State.Births,
+	}
+
+	for _, name := range a.State.WorldMap.OrderedCityNames() {
+		city := a.State.WorldMap.Cities[name]
+		cs := CitySnapshot{
+			Name:       name,
+			Neighbours: make(map[string]string, len(city.Neighbours)),
+			Population: city.Population,
+			Defense:    city.Defense,
+			Terrain:    city.Terrain,
+		}
+		for direction, neighbour := range city.Neighbours {
+			cs.Neighbours[direction] = neighbour.Name
+			if distance := city.Distance(direction); distance != 1 {
+				if cs.Distances == nil {
+					cs.Distances = make(map[string]int)
+				}
+				cs.Distances[direction] = distance
+			}
+		}
+		snapshot.Cities = append(snapshot.Cities, cs)
+	}
+
+	for _, id := range a.State.Aliens.IDs() {
+		alien := a.State.Aliens[id]
+		as := AlienSnapshot{ID: id, Moved: alien.Moved, Arrival: alien.Arrival, Faction: alien.Faction, Reproduced: alien.Reproduced}
+		if alien.CurrentCity != nil {
+			as.City = alien.CurrentCity.Name
+		}
+		if alien.Strategy != nil {
+			as.Strategy = alien.Strategy.String()
+		}
+		snapshot.Aliens = append(snapshot.Aliens, as)
+	}
+
+	for _, id := range a.State.Defenders.IDs() {
+		defender := a.State.Defenders[id]
+		snapshot.Defenders = append(snapshot.Defenders, DefenderSnapshot{
+			ID:      id,
+			City:    defender.CurrentCity.Name,
+			Moved:   defender.Moved,
+			Arrival: defender.Arrival,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snapshot)
+}
+
+// Restore replaces the state of the simulation by the one read from a snapshot, see Snapshot.
+// The configuration is kept, except for the seed which is taken from the snapshot.
+func (a *App) Restore(r io.Reader) error {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return fmt.Errorf("error reading snapshot: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+
+	directions, err := ParseDirections(snapshot.Directions)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	state := &AppState{
+		Aliens:            make(AlienSet, len(snapshot.Aliens)),
+		AlienLocations:    make(map[*City]AlienSet),
+		Defenders:         make(DefenderSet, len(snapshot.Defenders)),
+		DefenderLocations: make(map[*City]DefenderSet),
+		WorldMap:          &Map{Cities: make(map[string]*City), Directions: directions},
+		Tick:              snapshot.Tick,
+		DestroyedCities:   snapshot.DestroyedCities,
+		CityDamage:        snapshot.CityDamage,
+
+		PopulationLost:      snapshot.PopulationLost,
+		DefensesOverwhelmed: snapshot.DefensesOverwhelmed,
+		FactionLanded:       snapshot.FactionLanded,
+
+		NextAlienID: snapshot.NextAlienID,
+		WavesLanded: snapshot.WavesLanded,
+		Births:      snapshot.Births,
+	}
+	if state.CityDamage == nil {
+		state.CityDamage = make(map[string]int)
+	}
+
+	// Create the cities first, roads may lead to cities defined afterwards
+	for _, cs := range snapshot.Cities {
+		if _, found := state.WorldMap.Cities[cs.Name]; found {
+			return fmt.Errorf("invalid snapshot: city %s is defined more than once", cs.Name)
+		}
+		if cs.Population < 0 || cs.Defense < 0 {
+			return fmt.Errorf("invalid snapshot: city %s has a negative population or defense", cs.Name)
+		}
+		if _, err := ParseTerrain(string(cs.Terrain)); err != nil {
+			return fmt.Errorf("invalid snapshot: city %s: %w", cs.Name, err)
+		}
+		state.WorldMap.AddCity(&City{
+			Name:       cs.Name,
+			Neighbours: make(map[string]*City, len(cs.Neighbours)),
+			Population: cs.Population,
+			Defense:    cs.Defense,
+			Terrain:    cs.Terrain,
+		})
+	}
+	for _, cs := range snapshot.Cities {
+		city := state.WorldMap.Cities[cs.Name]
+		for direction, name := range cs.Neighbours {
+			neighbour, found := state.WorldMap.Cities[name]
+			if !found {
+				return fmt.Errorf("invalid snapshot: neighbour city %s of %s does not exist", name, cs.Name)
+			}
+			city.Neighbours[direction] = neighbour
+		}
+		for direction, distance := range cs.Distances {
+			if distance <= 0 {
+				return fmt.Errorf("invalid snapshot: road %s of %s has distance %d", direction, cs.Name, distance)
+			}
+			city.SetDistance(direction, distance)
+		}
+	}
+
+	for _, as := range snapshot.Aliens {
+		if _, found := state.Aliens[as.ID]; found {
+			return fmt.Errorf("invalid snapshot: alien %d is defined more than once", as.ID)
+		}
+		city, found := state.WorldMap.Cities[as.City]
+		if !found {
+			return fmt.Errorf("invalid snapshot: city %s of alien %d does not exist", as.City, as.ID)
+		}
+
+		alien := &Alien{ID: as.ID, CurrentCity: city, Moved: as.Moved, Arrival: as.Arrival, Faction: as.Faction, Reproduced: as.Reproduced}
+		if as.Strategy != "" {
+			strategy, err := ParseStrategy(as.Strategy)
+			if err != nil {
+				return fmt.Errorf("invalid snapshot: alien %d: %w", as.ID, err)
+			}
+			alien.Strategy = withVocabulary(strategy, state.WorldMap.Vocabulary())
+		}
+
+		state.Aliens[alien.ID] = alien
+		if alien.ID >= state.NextAlienID {
+			state.NextAlienID = alien.ID + 1
+		}
+		if alien.InTransit() {
+			continue
+		}
+		if location, found := state.AlienLocations[city]; found {
+			location[alien.ID] = alien
+		} else {
+			state.AlienLocations[city] = AlienSet{alien.ID: alien}
+		}
+	}
+
+	for _, ds := range snapshot.Defenders {
+		if _, found := state.Defenders[ds.ID]; found {
+			return fmt.Errorf("invalid snapshot: defender %d is defined more than once", ds.ID)
+		}
+		city, found := state.WorldMap.Cities[ds.City]
+		if !found {
+			return fmt.Errorf("invalid snapshot: city %s of defender %d does not exist", ds.City, ds.ID)
+		}
+
+		defender := &Defender{ID: ds.ID, CurrentCity: city, Moved: ds.Moved, Arrival: ds.Arrival}
+		state.Defenders[defender.ID] = defender
+		if defender.InTransit() {
+			continue
+		}
+		if location, found := state.DefenderLocations[city]; found {
+			location[defender.ID] = defender
+		} else {
+			state.DefenderLocations[city] = DefenderSet{defender.ID: defender}
+		}
+	}
+
+	a.State = state
+	a.SetSeed(snapshot.Seed)
+	a.rngSrc.skip(snapshot.Draws)
+	a.Cfg.Seed = snapshot.Seed
+
+	return nil
+}
+
+// SnapshotToFile writes a snapshot of the simulation to a file, see Snapshot
+func (a *App) SnapshotToFile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot file: %w", err)
+	}
+	if err := a.Snapshot(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// RestoreFromFile restores the simulation from a snapshot file, see Restore
+func (a *App) RestoreFromFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	return a.Restore(file)
+}
+
+// snapshotRequest is a snapshot to take between two ticks of a run
+type snapshotRequest struct {
+	w   io.Writer
+	err chan error
+}
+
+// RequestSnapshot writes a snapshot of a running simulation between two ticks.
+// It is safe to call concurrently with Run and blocks until the snapshot is written.
+// Once the run has ended, the snapshot is written right away.
+func (a *App) RequestSnapshot(w io.Writer) error {
+	req := snapshotRequest{w: w, err: make(chan error, 1)}
+	for {
+		select {
+		case a.snapshotReqs <- req:
+			return <-req.err
+		case <-a.Done():
+			// no run can start while the lock is held
+			a.mu.Lock()
+			if !a.IsRunning() {
+				defer a.mu.Unlock()
+				return a.Snapshot(w)
+			}
+			a.mu.Unlock()
+		}
+	}
+}
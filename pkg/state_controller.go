@@ -16,6 +16,16 @@ type StateController struct {
 	// while the printer is used to print messages to the user
 	// usually to stdout (or to other writers, check cmd/tui/tui.go for example)
 	printer *logger.Logger
+
+	// recording and recorded collect the events of a tick, see App.Step
+	recording bool
+	recorded  []Event
+
+	// strategy chooses where aliens go next, unless they have their own strategy
+	strategy MovementStrategy
+
+	// defenderStrategy chooses where defenders go next
+	defenderStrategy MovementStrategy
 }
 
 // DestroyAlien destroys an alien and removes it from the city
@@ -28,6 +38,12 @@ func (sc *StateController) DestroyAlien(alienID int) error {
 	if alien, exists := sc.app.State.Aliens[alienID]; !exists {
 		return fmt.Errorf("alien %d does not exist", alienID)
 	} else {
+		e := Event{Type: EventAlienDestroyed, Alien: alienID}
+		if alien.CurrentCity != nil {
+			e.City = alien.CurrentCity.Name
+		}
+		sc.emit(e)
+
 		delete(sc.app.State.AlienLocations[alien.CurrentCity], alienID)
 		delete(sc.app.State.Aliens, alienID)
 	}
@@ -36,76 +52,172 @@ func (sc *StateController) DestroyAlien(alienID int) error {
 }
 
 // DestroyCity destroys a city and removes it from the world map
-// as well as it destroys all aliens in the city.
+// as well as it destroys all aliens and defenders in the city and on the road to it.
 func (sc *StateController) DestroyCity(cityName string) error {
 	city, found := sc.app.State.WorldMap.Cities[cityName]
 	if !found {
 		return fmt.Errorf("City not found")
 	}
 
+	alienIDs := sc.app.State.AlienLocations[city].IDs()
+	sc.emit(Event{Type: EventCityDestroyed, City: cityName, Aliens: alienIDs})
+
 	msg := fmt.Sprintf("City %s has been destroyed by aliens: ", cityName)
-	for _, alien := range sc.app.State.AlienLocations[city] {
-		msg += fmt.Sprintf("%d ", alien.ID)
-		sc.DestroyAlien(alien.ID)
+	for _, id := range alienIDs {
+		msg += fmt.Sprintf("%d ", id)
+		sc.DestroyAlien(id)
+	}
+
+	// the aliens on the road have nowhere to arrive
+	for _, id := range sc.app.State.Aliens.IDs() {
+		if alien := sc.app.State.Aliens[id]; alien.InTransit() && alien.CurrentCity == city {
+			sc.DestroyAlien(id)
+		}
 	}
 
+	sc.destroyDefendersOf(city)
+
 	if sc.printer != nil {
 		sc.printer.Log(msg)
 	}
 
 	delete(sc.app.State.AlienLocations, city)
 	delete(sc.app.State.WorldMap.Cities, cityName)
+	sc.app.State.DestroyedCities = append(sc.app.State.DestroyedCities, cityName)
+	sc.app.State.PopulationLost += city.Population
+	sc.app.State.DefensesOverwhelmed += city.DefenseLevel()
 	for _, neighbour := range city.Neighbours {
 		for dir, neighbourNeighbour := range neighbour.Neighbours {
 			if neighbourNeighbour.Name == cityName {
 				delete(neighbour.Neighbours, dir)
+				delete(neighbour.Distances, dir)
 			}
 		}
 	}
 	return nil
 }
 
-// MoveAlienToNextCity moves an alien to a city.
+// MoveAlienToNextCity moves an alien to the city chosen by its movement strategy.
 // If the alien is not in the city, it returns an error.
 // If the city does not exist, it returns an error.
 // If the city is isolated, it returns an error.
+// If the strategy decides to stay put, the alien does not move.
 // Otherwise, it moves the alien to the city.
 func (sc *StateController) MoveAlienToNextCity(alien *Alien) error {
+	nextCity, err := sc.NextCity(alien)
+	if err != nil || nextCity == nil {
+		return err
+	}
+
+	sc.emit(Event{Type: EventAlienMoved, Alien: alien.ID, From: alien.CurrentCity.Name, City: nextCity.Name})
+	sc.moveAlien(alien, nextCity)
+
+	return nil
+}
+
+// NextCity returns the city an alien moves to next following its strategy,
+// or nil if it stays put, held back by the terrain of the city for instance (see Terrain).
+// The state is left untouched.
+func (sc *StateController) NextCity(alien *Alien) (*City, error) {
 	if alien == nil {
-		return fmt.Errorf("alien is nil")
+		return nil, fmt.Errorf("alien is nil")
 	}
 
 	_, found := sc.app.State.Aliens[alien.ID]
 	if !found {
-		return fmt.Errorf("alien %d does not exist in the world", alien.ID)
+		return nil, fmt.Errorf("alien %d does not exist in the world", alien.ID)
+	}
+
+	if alien.InTransit() {
+		// still on the road
+		return nil, nil
 	}
 
-	// Move the alien
 	_, found = sc.app.State.AlienLocations[alien.CurrentCity]
 	if !found {
-		return fmt.Errorf("alien %d did not land in any city", alien.ID)
+		return nil, fmt.Errorf("alien %d did not land in any city", alien.ID)
 	}
 
-	neighbour, err := GetRandomNeighbor(alien.CurrentCity)
-	if err != nil {
-		return err
+	neighbour, err := sc.StrategyFor(alien).NextCity(sc, alien.CurrentCity, sc.app.rng)
+	if err != nil || neighbour == nil {
+		return nil, err
 	}
 
 	nextCity, found := sc.app.State.WorldMap.Cities[neighbour.Name]
 	if !found {
-		return fmt.Errorf("city %s does not exist in world map", neighbour.Name)
+		return nil, fmt.Errorf("city %s does not exist in world map", neighbour.Name)
 	}
 
+	// the terrain may hold the alien back, draw only if needed
+	// so that maps without terrain do not consume the random source
+	if passability := nextCity.Terrain.Passability(); passability < 1 && sc.app.rng.Float64() >= passability {
+		return nil, nil
+	}
+
+	return nextCity, nil
+}
+
+// moveAlien moves an alien to the given city and updates the alien locations.
+// On a road longer than a tick the alien stays on the road until it arrives, see ArriveAliens.
+func (sc *StateController) moveAlien(alien *Alien, nextCity *City) {
+	distance := alien.CurrentCity.DistanceTo(nextCity)
+
 	delete(sc.app.State.AlienLocations[alien.CurrentCity], alien.ID)
 	alien.CurrentCity = nextCity
-	alien.Moved++
+	alien.Moved += distance
+	if distance > 1 {
+		alien.Arrival = sc.app.State.Tick + distance - 1
+		return
+	}
+	sc.placeAlien(alien)
+}
+
+// ArriveAliens places the aliens reaching the end of their road in the current tick
+// in their city, in ID order.
+func (sc *StateController) ArriveAliens() {
+	for _, id := range sc.app.State.Aliens.IDs() {
+		alien := sc.app.State.Aliens[id]
+		if alien.InTransit() && alien.Arrival <= sc.app.State.Tick {
+			sc.emit(Event{Type: EventAlienArrived, Alien: id, City: alien.CurrentCity.Name})
+			sc.arriveAlien(alien)
+		}
+	}
+}
+
+// arriveAlien takes an alien off the road and places it in its city
+func (sc *StateController) arriveAlien(alien *Alien) {
+	alien.Arrival = 0
+	sc.placeAlien(alien)
+}
+
+// placeAlien adds an alien to the locations of its current city
+func (sc *StateController) placeAlien(alien *Alien) {
+	nextCity := alien.CurrentCity
 	if nextCityAliens, found := sc.app.State.AlienLocations[nextCity]; found {
 		nextCityAliens[alien.ID] = alien
 	} else {
 		sc.app.State.AlienLocations[nextCity] = AlienSet{alien.ID: alien}
 	}
+}
 
-	return nil
+// emit records an event of the current tick in the app's journal, if any,
+// and publishes it to the subscribers of the app.
+func (sc *StateController) emit(e Event) {
+	e.Tick = sc.app.State.Tick
+
+	if sc.app.journal != nil {
+		if err := sc.app.journal.Append(e); err != nil && sc.app.logger != nil {
+			sc.app.logger.Logf("error: journal: %v", err)
+		}
+	}
+
+	if sc.recording {
+		sc.recorded = append(sc.recorded, e)
+	}
+
+	if sc.app.bus != nil {
+		sc.app.bus.Publish(e)
+	}
 }
 
 // AreAllAliensDestroyed returns true if all aliens are destroyed.
@@ -123,9 +235,22 @@ func (sc *StateController) IsWorldDestroyed() bool {
 	return len(sc.app.State.WorldMap.Cities) == 0
 }
 
+// IsStuck returns true if an alien can never move again: it is trapped,
+// or its strategy forbids every road left from its city.
+func (sc *StateController) IsStuck(alien *Alien) bool {
+	if alien.IsTrapped() {
+		return true
+	}
+	if alien.InTransit() {
+		return false
+	}
+	strategy, ok := sc.StrategyFor(alien).(confiningStrategy)
+	return ok && !strategy.CanLeave(alien.CurrentCity)
+}
+
 // IsAlienMovementLimitReached returns true if all aliens have reached
 // the maximum number of moves.
-// This method does not count trapped aliens.
+// This method does not count stuck aliens, see IsStuck.
 func (sc *StateController) IsAlienMovementLimitReached() bool {
 	if len(sc.app.State.Aliens) == 0 {
 		return false
@@ -133,7 +258,7 @@ func (sc *StateController) IsAlienMovementLimitReached() bool {
 
 	trapped := 0
 	for _, alien := range sc.app.State.Aliens {
-		if alien.IsTrapped() {
+		if sc.IsStuck(alien) {
 			trapped++
 		} else if alien != nil && alien.Moved < sc.app.Cfg.MaxMoves {
 			return false
@@ -143,39 +268,44 @@ func (sc *StateController) IsAlienMovementLimitReached() bool {
 	return trapped != len(sc.app.State.Aliens)
 }
 
-// AreRemainingAliensTrapped returns true if all remaining aliens are trapped.
+// AreRemainingAliensTrapped returns true if all remaining aliens are stuck, see IsStuck.
 func (sc *StateController) AreRemainingAliensTrapped() bool {
 	if len(sc.app.State.Aliens) == 0 {
 		return false
 	}
 
 	for _, alien := range sc.app.State.Aliens {
-		if alien != nil && !alien.IsTrapped() {
+		if alien != nil && !sc.IsStuck(alien) {
 			return false
 		}
 	}
 	return true
 }
 
-// SimulationResult returns a string describing the simulation termination reason.
-func (sc *StateController) SimulationResult() string {
+// TerminationReason returns the simulation termination reason deduced from the state.
+func (sc *StateController) TerminationReason() TerminationReason {
 	if sc.IsWorldDestroyed() {
-		return "The world has been destroyed"
+		return ReasonWorldDestroyed
 	} else if sc.AreAllAliensDestroyed() {
-		return "All aliens have been destroyed"
+		return ReasonAliensDestroyed
 	} else if sc.IsAlienMovementLimitReached() {
-		return "Alien movement limit reached"
+		return ReasonMovementLimit
 	} else if sc.AreRemainingAliensTrapped() {
-		return "All remaining aliens are trapped"
+		return ReasonAliensTrapped
 	} else {
-		return "Unknown"
+		return ReasonUnknown
 	}
 }
 
-// CopyState is a state getter, returns a copy of the state
+// SimulationResult returns a string describing the simulation termination reason.
+func (sc *StateController) SimulationResult() string {
+	return string(sc.TerminationReason())
+}
+
+// CopyState is a state getter, returns a deep copy of the state, see AppState.Clone
 // made public for testing
 func (sc *StateController) CopyState() AppState {
-	return *sc.app.State
+	return sc.app.State.Clone()
 }
 
 // BroadcastStateChanges broadcasts the state changes to the state channel.
@@ -192,6 +322,27 @@ func (sc *StateController) ListenForStateUpdates() chan AppState {
 	return sc.app.stateCh
 }
 
+// StrategyFor returns the movement strategy of an alien.
+func (sc *StateController) StrategyFor(alien *Alien) MovementStrategy {
+	if alien.Strategy != nil {
+		return alien.Strategy
+	}
+	return sc.Strategy()
+}
+
+// Strategy returns the default movement strategy, uniform if none is set.
+func (sc *StateController) Strategy() MovementStrategy {
+	if sc.strategy == nil {
+		return UniformStrategy{}
+	}
+	return sc.strategy
+}
+
+// SetStrategy sets the default movement strategy.
+func (sc *StateController) SetStrategy(strategy MovementStrategy) {
+	sc.strategy = strategy
+}
+
 // App returns the app.
 func (sc *StateController) App() *App {
 	return sc.app
@@ -0,0 +1,160 @@
+package simulation
+
+import (
+	"errors"
+)
+
+// ErrSimulationEnded is returned when stepping a simulation that has already ended
+var ErrSimulationEnded = errors.New("simulation has ended")
+
+// TickReport is what happened during a tick, see App.Step
+type TickReport struct {
+	Tick               int               `json:"tick"`
+	Events             []Event           `json:"events"` // events emitted during the tick, in order
+	Moves              int               `json:"moves"`  // number of aliens that moved
+	DestroyedCities    []string          `json:"destroyed_cities,omitempty"`
+	DestroyedAliens    []int             `json:"destroyed_aliens,omitempty"`
+	DestroyedDefenders []int             `json:"destroyed_defenders,omitempty"`
+	Reason             TerminationReason `json:"reason,omitempty"` // set once the simulation has ended
+}
+
+// Ended returns true if the simulation ended instead of ticking
+func (r TickReport) Ended() bool {
+	return r.Reason != ""
+}
+
+// newTickReport summarizes the events of a tick
+func newTickReport(tick int, events []Event) TickReport {
+	report := TickReport{Tick: tick, Events: events}
+	for _, e := range events {
+		switch e.Type {
+		case EventAlienMoved:
+			report.Moves++
+		case EventCityDestroyed:
+			report.DestroyedCities = append(report.DestroyedCities, e.City)
+		case EventAlienDestroyed:
+			report.DestroyedAliens = append(report.DestroyedAliens, e.Alien)
+		case EventDefenderDestroyed:
+			report.DestroyedDefenders = append(report.DestroyedDefenders, e.Defender)
+		case EventSimulationEnded:
+			report.Reason = TerminationReason(e.Reason)
+		}
+	}
+	return report
+}
+
+// Step executes exactly one tick of the simulation: collision resolution then movement.
+// If the simulation is over, it ends it instead, reporting why.
+// It returns ErrSimulationEnded once the simulation has ended.
+func (a *App) Step() (TickReport, error) {
+	if a.reason != "" {
+		return TickReport{Tick: a.State.Tick, Reason: a.reason}, ErrSimulationEnded
+	}
+
+	a.stateCtrl.startRecording()
+	if reason := a.checkTermination(); reason != "" {
+		a.end(reason)
+	} else {
+		a.tick()
+	}
+	return newTickReport(a.State.Tick, a.stateCtrl.stopRecording()), nil
+}
+
+// RunUntil steps the simulation until the predicate holds for the report of a tick
+// or the simulation ends, and returns the last report.
+// Unlike Run, it neither sleeps nor serves the snapshot requests.
+func (a *App) RunUntil(predicate func(TickReport) bool) (TickReport, error) {
+	for {
+		report, err := a.Step()
+		if err != nil || report.Ended() || predicate(report) {
+			return report, err
+		}
+	}
+}
+
+// checkTermination returns why the simulation is over, or an empty reason if it goes on
+func (a *App) checkTermination() TerminationReason {
+	// Pending reinforcement waves keep the simulation going without aliens
+	// or with every alien trapped, see App.LandWave
+	pending := a.stateCtrl.HasPendingWaves()
+
+	// Check if all aliens have been destroyed
+	if !pending && a.stateCtrl.AreAllAliensDestroyed() {
+		a.logger.Log("All aliens have been destroyed.")
+		return a.stateCtrl.TerminationReason()
+	}
+
+	// Check if all aliens have moved 10,000 times
+	if a.stateCtrl.IsAlienMovementLimitReached() {
+		a.logger.Log("All aliens have moved 10,000 times.")
+		return a.stateCtrl.TerminationReason()
+	}
+
+	if !pending && a.stateCtrl.AreRemainingAliensTrapped() {
+		a.logger.Log("All remaining aliens are trapped.")
+		return a.stateCtrl.TerminationReason()
+	}
+
+	return ""
+}
+
+// tick executes a tick of the simulation
+func (a *App) tick() {
+	a.State.Tick++
+
+	// Land the reinforcements due this tick, if any
+	a.LandWave()
+
+	// Resolve the encounters of the cities holding enough aliens, see CollisionRules
+	if err := a.stateCtrl.ResolveCollisions(); err != nil {
+		a.logger.Logf("error: %v", err)
+	}
+
+	// The surviving aliens old enough give birth before moving on, see ReproduceAliens
+	a.stateCtrl.ReproduceAliens()
+
+	// Move aliens around in the map, see TickMode
+	if err := a.stateCtrl.MoveAliens(); err != nil {
+		a.logger.Logf("error: %v", err)
+	}
+
+	// then the defenders, see Defender
+	if err := a.stateCtrl.MoveDefenders(); err != nil {
+		a.logger.Logf("error: %v", err)
+	}
+
+	// Broadcast state changes to the observers
+	a.stateCtrl.emit(Event{Type: EventTickEnded})
+	a.stateCtrl.BroadcastStateChanges()
+}
+
+// end ends the simulation: it records why, closes the journal and releases the waiters
+func (a *App) end(reason TerminationReason) {
+	a.reason = reason
+
+	// Record the end of the simulation and close the journal
+	a.stateCtrl.emit(Event{Type: EventSimulationEnded, Reason: string(reason)})
+	if a.journal != nil {
+		if err := a.journal.Close(); err != nil {
+			a.logger.Logf("error: journal: %v", err)
+		}
+		a.journal = nil
+	}
+
+	// Indicate that the main loop has finished by closing the channel
+	close(a.done)
+}
+
+// startRecording starts recording the emitted events, see stopRecording
+func (sc *StateController) startRecording() {
+	sc.recording = true
+	sc.recorded = nil
+}
+
+// stopRecording stops recording the emitted events and returns them
+func (sc *StateController) stopRecording() []Event {
+	events := sc.recorded
+	sc.recording = false
+	sc.recorded = nil
+	return events
+}
@@ -0,0 +1,335 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MovementStrategy chooses where an alien goes next.
+type MovementStrategy interface {
+	// NextCity returns the neighbour of from to move to, or nil to stay put.
+	// It returns ErrNoNeighbours if from has no neighbour left.
+	NextCity(sc *StateController, from *City, rng *rand.Rand) (*City, error)
+
+	// String returns the spec of the strategy, see ParseStrategy
+	String() string
+}
+
+// confiningStrategy is implemented by the strategies that may never leave some cities,
+// see StateController.IsStuck
+type confiningStrategy interface {
+	// CanLeave returns false if the strategy stays put in the city for good
+	CanLeave(from *City) bool
+}
+
+// sortedDirections returns the directions of the neighbours of a city in alphabetical order
+// so that a pick only depends on the random source
+func sortedDirections(city *City) ([]string, error) {
+	if city == nil {
+		return nil, fmt.Errorf("city is nil")
+	}
+
+	if len(city.Neighbours) == 0 {
+		return nil, fmt.Errorf("%w. city=%s", ErrNoNeighbours, city.Name)
+	}
+
+	directions := make([]string, 0, len(city.Neighbours))
+	for direction, neighbour := range city.Neighbours {
+		if neighbour == nil {
+			return nil, fmt.Errorf("city %s has a nil neighbour", city.Name)
+		}
+		directions = append(directions, direction)
+	}
+	sort.Strings(directions)
+
+	return directions, nil
+}
+
+// UniformStrategy moves to a neighbour picked uniformly at random.
+type UniformStrategy struct{}
+
+func (UniformStrategy) NextCity(sc *StateController, from *City, rng *rand.Rand) (*City, error) {
+	return GetRandomNeighbor(rng, from)
+}
+
+func (UniformStrategy) String() string {
+	return "uniform"
+}
+
+// WeightedStrategy moves to a random neighbour, each direction being picked
+// proportionally to its weight. Directions without weight weigh 1.
+type WeightedStrategy struct {
+	Weights    map[string]float64
+	Directions *Directions // direction vocabulary ordering the spec, compass if nil
+}
+
+func (s WeightedStrategy) weight(direction string) float64 {
+	if w, found := s.Weights[direction]; found {
+		return w
+	}
+	return 1
+}
+
+func (s WeightedStrategy) NextCity(sc *StateController, from *City, rng *rand.Rand) (*City, error) {
+	directions, err := sortedDirections(from)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0.0
+	for _, direction := range directions {
+		total += s.weight(direction)
+	}
+	if total <= 0 {
+		// every road left is forbidden, stay put, see CanLeave
+		return nil, nil
+	}
+
+	pick := rng.Float64() * total
+	for _, direction := range directions {
+		pick -= s.weight(direction)
+		if pick < 0 {
+			return from.Neighbours[direction], nil
+		}
+	}
+
+	return from.Neighbours[directions[len(directions)-1]], nil
+}
+
+// CanLeave returns true if a road left from the city has a weight
+func (s WeightedStrategy) CanLeave(from *City) bool {
+	for direction := range from.Neighbours {
+		if s.weight(direction) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s WeightedStrategy) String() string {
+	directions := make([]string, 0, len(s.Weights))
+	for direction := range s.Weights {
+		directions = append(directions, direction)
+	}
+	if s.Directions == nil {
+		SortDirections(directions)
+	} else {
+		s.Directions.Sort(directions)
+	}
+
+	weights := make([]string, 0, len(directions))
+	for _, direction := range directions {
+		weights = append(weights, fmt.Sprintf("%s=%s", direction, strconv.FormatFloat(s.Weights[direction], 'g', -1, 64)))
+	}
+	return "weighted:" + strings.Join(weights, ",")
+}
+
+// SeekStrategy moves towards the nearest city holding other aliens,
+// or at random if no other alien can be reached.
+type SeekStrategy struct{}
+
+func (SeekStrategy) NextCity(sc *StateController, from *City, rng *rand.Rand) (*City, error) {
+	directions, err := sortedDirections(from)
+	if err != nil {
+		return nil, err
+	}
+
+	// Breadth first search, remembering the first step leading to each city
+	firstStep := make(map[*City]*City)
+	visited := map[*City]bool{from: true}
+	queue := make([]*City, 0, len(directions))
+	for _, direction := range directions {
+		neighbour := from.Neighbours[direction]
+		if !visited[neighbour] {
+			visited[neighbour] = true
+			firstStep[neighbour] = neighbour
+			queue = append(queue, neighbour)
+		}
+	}
+
+	for len(queue) > 0 {
+		city := queue[0]
+		queue = queue[1:]
+
+		if len(sc.app.State.AlienLocations[city]) > 0 {
+			return firstStep[city], nil
+		}
+
+		next, err := sortedDirections(city)
+		if err != nil {
+			continue
+		}
+		for _, direction := range next {
+			neighbour := city.Neighbours[direction]
+			if !visited[neighbour] {
+				visited[neighbour] = true
+				firstStep[neighbour] = firstStep[city]
+				queue = append(queue, neighbour)
+			}
+		}
+	}
+
+	return GetRandomNeighbor(rng, from)
+}
+
+func (SeekStrategy) String() string {
+	return "seek"
+}
+
+// AvoidCrowdedStrategy moves to the neighbour holding the fewest aliens,
+// picking at random among equally crowded neighbours.
+type AvoidCrowdedStrategy struct{}
+
+func (AvoidCrowdedStrategy) NextCity(sc *StateController, from *City, rng *rand.Rand) (*City, error) {
+	directions, err := sortedDirections(from)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*City
+	fewest := -1
+	for _, direction := range directions {
+		neighbour := from.Neighbours[direction]
+		crowd := len(sc.app.State.AlienLocations[neighbour])
+		if fewest < 0 || crowd < fewest {
+			fewest = crowd
+			candidates = candidates[:0]
+		}
+		if crowd == fewest {
+			candidates = append(candidates, neighbour)
+		}
+	}
+
+	return candidates[rng.Intn(len(candidates))], nil
+}
+
+func (AvoidCrowdedStrategy) String() string {
+	return "avoid"
+}
+
+// LazyStrategy stays put with the given chance, and moves following
+// the base strategy otherwise.
+type LazyStrategy struct {
+	StayChance float64
+	Base       MovementStrategy
+}
+
+func (s LazyStrategy) NextCity(sc *StateController, from *City, rng *rand.Rand) (*City, error) {
+	if _, err := sortedDirections(from); err != nil {
+		return nil, err
+	}
+
+	if rng.Float64() < s.StayChance {
+		return nil, nil
+	}
+
+	return s.Base.NextCity(sc, from, rng)
+}
+
+// CanLeave returns true if the base strategy can leave the city
+func (s LazyStrategy) CanLeave(from *City) bool {
+	if base, ok := s.Base.(confiningStrategy); ok {
+		return base.CanLeave(from)
+	}
+	return true
+}
+
+func (s LazyStrategy) String() string {
+	spec := "lazy:" + strconv.FormatFloat(s.StayChance, 'g', -1, 64)
+	if _, uniform := s.Base.(UniformStrategy); !uniform {
+		spec += ":" + s.Base.String()
+	}
+	return spec
+}
+
+// ParseStrategy parses a movement strategy spec:
+//
+//	uniform                      random neighbour (default)
+//	weighted:north=2,east=0.5    random neighbour weighted by direction, 1 by default
+//	seek                         towards the nearest other alien
+//	avoid                        towards the least crowded neighbour
+//	lazy:0.3[:<strategy>]        stays put 30% of the time, moves following the strategy (uniform by default) otherwise
+func ParseStrategy(spec string) (MovementStrategy, error) {
+	name, args, _ := strings.Cut(spec, ":")
+
+	switch name {
+	case "", "uniform":
+		return UniformStrategy{}, nil
+	case "seek":
+		return SeekStrategy{}, nil
+	case "avoid":
+		return AvoidCrowdedStrategy{}, nil
+	case "weighted":
+		weights := make(map[string]float64)
+		total := 0.0
+		for _, pair := range strings.Split(args, ",") {
+			direction, value, found := strings.Cut(pair, "=")
+			if !found || direction == "" {
+				return nil, fmt.Errorf("invalid strategy %q: expected direction=weight, got %q", spec, pair)
+			}
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid strategy %q: invalid weight %q", spec, value)
+			}
+			weights[direction] = weight
+			total += weight
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("invalid strategy %q: every weight is zero, the aliens would never move", spec)
+		}
+		return WeightedStrategy{Weights: weights}, nil
+	case "lazy":
+		chance, baseSpec, _ := strings.Cut(args, ":")
+		stayChance, err := strconv.ParseFloat(chance, 64)
+		if err != nil || stayChance < 0 || stayChance >= 1 {
+			return nil, fmt.Errorf("invalid strategy %q: stay chance must be in [0, 1), got %q", spec, chance)
+		}
+		base, err := ParseStrategy(baseSpec)
+		if err != nil {
+			return nil, err
+		}
+		return LazyStrategy{StayChance: stayChance, Base: base}, nil
+	default:
+		return nil, fmt.Errorf("unknown movement strategy %q", spec)
+	}
+}
+
+// withVocabulary returns the strategy printing its weights in the order of the direction vocabulary of the map
+func withVocabulary(strategy MovementStrategy, directions *Directions) MovementStrategy {
+	switch s := strategy.(type) {
+	case WeightedStrategy:
+		s.Directions = directions
+		return s
+	case LazyStrategy:
+		s.Base = withVocabulary(s.Base, directions)
+		return s
+	default:
+		return strategy
+	}
+}
+
+// ParseAlienStrategies parses per alien strategy specs of the form <alien id>=<strategy>
+func ParseAlienStrategies(specs []string) (map[int]MovementStrategy, error) {
+	strategies := make(map[int]MovementStrategy, len(specs))
+	for _, spec := range specs {
+		id, strategySpec, found := strings.Cut(spec, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid alien strategy %q: expected <alien id>=<strategy>", spec)
+		}
+
+		alienID, err := strconv.Atoi(id)
+		if err != nil || alienID < 0 {
+			return nil, fmt.Errorf("invalid alien strategy %q: invalid alien id %q", spec, id)
+		}
+
+		strategy, err := ParseStrategy(strategySpec)
+		if err != nil {
+			return nil, err
+		}
+		strategies[alienID] = strategy
+	}
+	return strategies, nil
+}
@@ -0,0 +1,182 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// TickMode decides how the aliens move during a tick
+type TickMode string
+
+const (
+	// TickSequential moves the aliens one after the other in ID order,
+	// each alien seeing the moves of the aliens before it
+	TickSequential TickMode = "sequential"
+	// TickSimultaneous computes the moves of every alien first
+	// and applies them all at once
+	TickSimultaneous TickMode = "simultaneous"
+)
+
+// ParseTickMode parses a tick mode, an empty mode is sequential
+func ParseTickMode(mode string) (TickMode, error) {
+	switch TickMode(mode) {
+	case "", TickSequential:
+		return TickSequential, nil
+	case TickSimultaneous:
+		return TickSimultaneous, nil
+	default:
+		return "", fmt.Errorf("unknown tick mode %q", mode)
+	}
+}
+
+// Move is an alien travelling along the road from a city to a neighbour during a tick
+type Move struct {
+	Alien    *Alien
+	From, To *City
+}
+
+// road identifies the road between two cities regardless of the direction of travel
+type road struct{ a, b string }
+
+func roadOf(m Move) road {
+	if m.From.Name < m.To.Name {
+		return road{m.From.Name, m.To.Name}
+	}
+	return road{m.To.Name, m.From.Name}
+}
+
+// MoveAliens moves every alien once, following the tick mode of the configuration,
+// then the aliens reaching the end of their road arrive, see ArriveAliens.
+// Aliens without neighbours or on the road stay put, the other errors are joined.
+func (sc *StateController) MoveAliens() error {
+	mode, err := ParseTickMode(string(sc.app.Cfg.TickMode))
+	if err != nil {
+		return err
+	}
+	defer sc.ArriveAliens()
+
+	var errs []error
+	if mode == TickSequential {
+		// in ID order so that the random source is consumed deterministically
+		for _, id := range sc.app.State.Aliens.IDs() {
+			if alien := sc.app.State.Aliens[id]; alien != nil {
+				if err := sc.MoveAlienToNextCity(alien); err != nil && !errors.Is(err, ErrNoNeighbours) {
+					errs = append(errs, err)
+				}
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	moves, err := sc.PlanMoves()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if sc.app.Cfg.RoadEncounters {
+		if moves, err = sc.ResolveRoadEncounters(moves); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	sc.ApplyMoves(moves)
+
+	return errors.Join(errs...)
+}
+
+// PlanMoves computes the next move of every alien against the current state,
+// in ID order. Aliens staying put or without neighbours have no move.
+func (sc *StateController) PlanMoves() ([]Move, error) {
+	var (
+		moves []Move
+		errs  []error
+	)
+	for _, id := range sc.app.State.Aliens.IDs() {
+		alien := sc.app.State.Aliens[id]
+		nextCity, err := sc.NextCity(alien)
+		if err != nil {
+			if !errors.Is(err, ErrNoNeighbours) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if nextCity != nil {
+			moves = append(moves, Move{Alien: alien, From: alien.CurrentCity, To: nextCity})
+		}
+	}
+	return moves, errors.Join(errs...)
+}
+
+// ResolveRoadEncounters makes the aliens travelling in opposite directions
+// along the same road fight: a single random alien of the road survives
+// and carries on its move. It returns the moves left.
+func (sc *StateController) ResolveRoadEncounters(moves []Move) ([]Move, error) {
+	byRoad := make(map[road][]Move)
+	for _, m := range moves {
+		byRoad[roadOf(m)] = append(byRoad[roadOf(m)], m)
+	}
+
+	roads := make([]road, 0, len(byRoad))
+	for r := range byRoad {
+		roads = append(roads, r)
+	}
+	sort.Slice(roads, func(i, j int) bool {
+		if roads[i].a != roads[j].a {
+			return roads[i].a < roads[j].a
+		}
+		return roads[i].b < roads[j].b
+	})
+
+	defeated := make(map[int]bool)
+	for _, r := range roads {
+		travellers := byRoad[r]
+
+		headOn := false
+		for _, m := range travellers[1:] {
+			if m.From != travellers[0].From {
+				headOn = true
+				break
+			}
+		}
+		if !headOn {
+			continue
+		}
+
+		// travellers are in ID order, see PlanMoves
+		ids := make([]int, len(travellers))
+		for i, m := range travellers {
+			ids[i] = m.Alien.ID
+		}
+		sc.emit(Event{Type: EventRoadEncounter, From: r.a, City: r.b, Aliens: ids})
+
+		winner := ids[sc.app.rng.Intn(len(ids))]
+		for _, id := range ids {
+			if id == winner {
+				continue
+			}
+			if err := sc.DestroyAlien(id); err != nil {
+				return nil, err
+			}
+			defeated[id] = true
+		}
+
+		if sc.printer != nil {
+			sc.printer.Log(fmt.Sprintf("Aliens %v met on the road between %s and %s, alien %d survived", ids, r.a, r.b, winner))
+		}
+	}
+
+	left := make([]Move, 0, len(moves))
+	for _, m := range moves {
+		if !defeated[m.Alien.ID] {
+			left = append(left, m)
+		}
+	}
+	return left, nil
+}
+
+// ApplyMoves moves the aliens along their planned roads
+func (sc *StateController) ApplyMoves(moves []Move) {
+	for _, m := range moves {
+		sc.emit(Event{Type: EventAlienMoved, Alien: m.Alien.ID, From: m.From.Name, City: m.To.Name})
+		sc.moveAlien(m.Alien, m.To)
+	}
+}
@@ -1,11 +1,47 @@
 package simulation
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // City is a city in the world map
 type City struct {
 	Name       string
 	Neighbours map[string]*City
+	Distances  map[string]int // travel time in ticks of the roads by direction, 1 if not set
+
+	// attributes of the map file, see SetAttribute
+	Population int
+	Defense    int
+	Terrain    Terrain // plains if empty
+}
+
+// Distance returns the travel time in ticks of the road in the given direction
+func (c *City) Distance(direction string) int {
+	if distance, found := c.Distances[direction]; found && distance > 0 {
+		return distance
+	}
+	return 1
+}
+
+// DistanceTo returns the travel time in ticks of the road to a neighbour,
+// 1 if the city is not a neighbour
+func (c *City) DistanceTo(neighbour *City) int {
+	for direction, city := range c.Neighbours {
+		if city == neighbour {
+			return c.Distance(direction)
+		}
+	}
+	return 1
+}
+
+// SetDistance sets the travel time in ticks of the road in the given direction
+func (c *City) SetDistance(direction string, distance int) {
+	if c.Distances == nil {
+		c.Distances = make(map[string]int)
+	}
+	c.Distances[direction] = distance
 }
 
 // String returns a string representation of the city
@@ -24,23 +60,95 @@ func (c *City) String() string {
 
 // Map is the world map
 type Map struct {
-	Cities map[string]*City
+	Cities     map[string]*City
+	Directions *Directions // direction vocabulary of the roads, compass if nil
+
+	order []string // city names in order of definition, see AddCity
+}
+
+// Vocabulary returns the direction vocabulary of the map
+func (m *Map) Vocabulary() *Directions {
+	if m.Directions == nil {
+		return DefaultDirections()
+	}
+	return m.Directions
+}
+
+// AddCity adds a city to the map, remembering the order of definition
+func (m *Map) AddCity(city *City) {
+	if _, found := m.Cities[city.Name]; !found {
+		m.order = append(m.order, city.Name)
+	}
+	m.Cities[city.Name] = city
+}
+
+// OrderedCityNames returns the names of the cities in the map in order of definition.
+// Cities added without AddCity come last, sorted alphabetically.
+func (m *Map) OrderedCityNames() []string {
+	names := make([]string, 0, len(m.Cities))
+	seen := make(map[string]bool, len(m.order))
+	for _, name := range m.order {
+		if _, found := m.Cities[name]; found && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	for _, name := range m.CityNames() {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CityNames returns the names of the cities in the map sorted alphabetically
+func (m *Map) CityNames() []string {
+	names := make([]string, 0, len(m.Cities))
+	for name := range m.Cities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // Alien is an alien in the world
 type Alien struct {
 	ID          int
 	CurrentCity *City
-	Moved       int
+	Moved       int              // distance travelled, in ticks of road
+	Strategy    MovementStrategy // overrides the strategy of the state controller when set
+	Arrival     int              // tick at which the alien reaches CurrentCity while on the road, 0 once in it
+	Faction     string           // faction of the alien, empty if factions are not in use, see ParseFactions
+	Reproduced  bool             // the alien has given birth, see StateController.ReproduceAliens
+}
+
+// InTransit returns true if the alien is still on the road to its current city
+func (a *Alien) InTransit() bool {
+	return a.Arrival > 0
 }
 
-// IsTrapped returns true if the alien is trapped in a city
+// IsTrapped returns true if the alien is trapped in a city.
+// An alien on the road is never trapped, it arrives somewhere.
 func (a *Alien) IsTrapped() bool {
 	if a.CurrentCity == nil {
 		panic("alien is not in any city")
 	}
+	if a.InTransit() {
+		return false
+	}
 	return len(a.CurrentCity.Neighbours) == 0
 }
 
 // AlienSet is a set of aliens
 type AlienSet map[int]*Alien
+
+// IDs returns the IDs of the aliens in the set in ascending order
+func (s AlienSet) IDs() []int {
+	ids := make([]int, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
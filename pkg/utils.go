@@ -1,55 +1,58 @@
 package simulation
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
 	"time"
 )
 
 // functions made public for testing
 
-// getRandomCity returns a random city from the map
-func GetRandomNeighbor(city *City) (*City, error) {
+// ErrNoNeighbours is returned when an alien cannot leave its city
+var ErrNoNeighbours = errors.New("city has no neighbours or neighbours have been destroyed")
+
+// GetRandomNeighbor returns a random neighbour of the city drawn from rng
+// directions are sorted so that the pick only depends on the random source
+func GetRandomNeighbor(rng *rand.Rand, city *City) (*City, error) {
+	if rng == nil {
+		return nil, fmt.Errorf("GetRandomNeighbor: random source is nil")
+	}
+
 	if city == nil {
 		return nil, fmt.Errorf("GetRandomNeighbor: city is nil")
 	}
 
 	if len(city.Neighbours) == 0 {
-		return nil, fmt.Errorf("GetRandomNeighbor: city has no neighbours or neighbours have been destroyed. city=%s", city.Name)
+		return nil, fmt.Errorf("GetRandomNeighbor: %w. city=%s", ErrNoNeighbours, city.Name)
+	}
+
+	directions := make([]string, 0, len(city.Neighbours))
+	for direction := range city.Neighbours {
+		directions = append(directions, direction)
 	}
+	sort.Strings(directions)
 
-	rand.Seed(time.Now().UnixNano())
-	index := rand.Intn(len(city.Neighbours))
-	i := 0
-	for neighbour := range city.Neighbours {
-		if i == index {
-			if city.Neighbours[neighbour] == nil {
-				return nil, fmt.Errorf("GetRandomNeighbor: city %s has a nil neighbour", city.Name)
-			}
-			return city.Neighbours[neighbour], nil
-		}
-		i++
+	neighbour := city.Neighbours[directions[rng.Intn(len(directions))]]
+	if neighbour == nil {
+		return nil, fmt.Errorf("GetRandomNeighbor: city %s has a nil neighbour", city.Name)
 	}
 
-	// This should not happen, but return nil as a safety measure
-	return nil, fmt.Errorf("getRandomNeighbor: could not find a random neighbour")
+	return neighbour, nil
 }
 
-// oppositeDirection returns the opposite direction of the given direction
-// used during map creation
+// OppositeDirection returns the opposite of a compass direction,
+// or an empty string for any other direction. See Directions for other vocabularies.
 func OppositeDirection(direction string) string {
-	switch direction {
-	case "north":
-		return "south"
-	case "south":
-		return "north"
-	case "east":
-		return "west"
-	case "west":
-		return "east"
-	default:
-		return ""
-	}
+	return DefaultDirections().Opposite(direction)
+}
+
+// SortDirections sorts directions in compass order (north, south, east, west),
+// other directions come last in alphabetical order. See Directions.Sort for other vocabularies.
+func SortDirections(directions []string) {
+	DefaultDirections().Sort(directions)
 }
 
 // removeSliceElement removes an element from a slice
@@ -65,7 +68,16 @@ func RemoveSliceElement[T any](slice []T, index int) []T {
 	return newSlice
 }
 
-// sleepMs sleeps for the given number of milliseconds
-func sleepMS(ms int) {
-	time.Sleep(time.Duration(ms) * time.Millisecond)
+// sleepContext sleeps for the given number of milliseconds
+// it returns early with the context error if the context is done
+func sleepContext(ctx context.Context, ms int) error {
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
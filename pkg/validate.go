@@ -0,0 +1,359 @@
+package simulation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Severity is the severity of a map diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is an issue found in a map file
+type Diagnostic struct {
+	File     string
+	Line     int // 1-based line number
+	Col      int // 1-based column of the offending token
+	Severity Severity
+	Message  string
+}
+
+// String returns the diagnostic in the file:line:col: severity: message format
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Col, d.Severity, d.Message)
+}
+
+// ValidationError is returned when a map fails strict validation
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+// Error lists all the diagnostics, one per line
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Diagnostics)+1)
+	lines = append(lines, fmt.Sprintf("map validation failed with %d diagnostics:", len(e.Diagnostics)))
+	for _, d := range e.Diagnostics {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors returns true if any diagnostic is an error
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// token is a whitespace separated field of a map line
+type token struct {
+	text string
+	col  int // 1-based
+}
+
+// tokenize splits a line into whitespace separated tokens, keeping their column
+func tokenize(line string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range line {
+		if r == ' ' || r == '\t' {
+			if start >= 0 {
+				tokens = append(tokens, token{text: line[start:i], col: start + 1})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: line[start:], col: start + 1})
+	}
+	return tokens
+}
+
+// link is a road declared in a map file, along with where it is declared
+type link struct {
+	from, direction, to string
+	distance            int // 0 if not given
+	line, col           int
+}
+
+// ValidateMap checks a map read from r and returns every issue found:
+// duplicate cities, malformed neighbours and city attributes, unknown directions and cities,
+// self-loops, contradictory links or distances, misplaced or invalid directions headers and blank lines.
+// Directions are checked against the @directions header of the map if any, or the compass otherwise.
+// filename is only used to report the diagnostics.
+func ValidateMap(r io.Reader, filename string) ([]Diagnostic, error) {
+	return ValidateMapWithDirections(r, filename, nil)
+}
+
+// ValidateMapWithDirections checks a map like ValidateMap, the directions of a map
+// without @directions header being checked against the given vocabulary (compass if nil).
+func ValidateMapWithDirections(r io.Reader, filename string, directions *Directions) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	report := func(line, col int, severity Severity, format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     filename,
+			Line:     line,
+			Col:      col,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Read all the lines first, neighbours may be defined after being referenced
+	var lines [][]token
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, tokenize(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	if directions == nil {
+		directions = DefaultDirections()
+	}
+
+	definedAt := make(map[string]int)
+	headerAt := 0
+	for i, tokens := range lines {
+		if len(tokens) == 0 {
+			report(i+1, 1, SeverityWarning, "blank line")
+			continue
+		}
+
+		name := tokens[0]
+		if name.text == DirectionsHeader {
+			fields := make([]string, 0, len(tokens))
+			for _, tok := range tokens {
+				fields = append(fields, tok.text)
+			}
+			declared, _, err := parseDirectionsHeader(fields)
+			switch {
+			case headerAt > 0:
+				report(i+1, name.col, SeverityError, "directions are already declared at line %d", headerAt)
+			case len(definedAt) > 0:
+				report(i+1, name.col, SeverityError, "%s must come before the cities", DirectionsHeader)
+			case err != nil:
+				report(i+1, name.col, SeverityError, "%v", err)
+			default:
+				directions = declared
+			}
+			headerAt = i + 1
+			continue
+		}
+
+		if strings.Contains(name.text, "=") {
+			report(i+1, name.col, SeverityError, "line must start with a city name, got %q", name.text)
+			continue
+		}
+		if line, found := definedAt[name.text]; found {
+			report(i+1, name.col, SeverityError, "city %s is already defined at line %d", name.text, line)
+			continue
+		}
+		definedAt[name.text] = i + 1
+	}
+
+	// Collect the declared links
+	var links []link
+	for i, tokens := range lines {
+		if len(tokens) == 0 || strings.Contains(tokens[0].text, "=") || tokens[0].text == DirectionsHeader {
+			continue
+		}
+
+		cityName := tokens[0].text
+		usedDirections := make(map[string]bool)
+		usedAttributes := make(map[string]bool)
+		for _, tok := range tokens[1:] {
+			if key, value, found := parseAttribute(tok.text); found {
+				if usedAttributes[key] {
+					report(i+1, tok.col, SeverityError, "attribute %s is set more than once for %s", key, cityName)
+					continue
+				}
+				usedAttributes[key] = true
+				if err := (&City{Name: cityName}).SetAttribute(key, value); err != nil {
+					report(i+1, tok.col, SeverityError, "%v", err)
+				}
+				continue
+			}
+
+			parts := strings.Split(tok.text, "=")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				report(i+1, tok.col, SeverityError, "invalid neighbour %q, expected direction=city", tok.text)
+				continue
+			}
+
+			direction := parts[0]
+			neighbour, distance, err := parseRoadTarget(parts[1])
+			if err != nil {
+				report(i+1, tok.col, SeverityError, "%v", err)
+				continue
+			}
+			if !directions.Has(direction) {
+				report(i+1, tok.col, SeverityError, "unknown direction %q", direction)
+				continue
+			}
+			if usedDirections[direction] {
+				report(i+1, tok.col, SeverityError, "direction %s is used more than once for %s", direction, cityName)
+				continue
+			}
+			usedDirections[direction] = true
+
+			if _, found := definedAt[neighbour]; !found {
+				report(i+1, tok.col, SeverityError, "neighbour city %s is not defined", neighbour)
+				continue
+			}
+			if neighbour == cityName {
+				report(i+1, tok.col, SeverityError, "city %s is its own %s neighbour", cityName, direction)
+				continue
+			}
+
+			links = append(links, link{from: cityName, direction: direction, to: neighbour, distance: distance, line: i + 1, col: tok.col})
+		}
+	}
+
+	// Every link implies its back-link, both must agree with all the other links
+	type slot struct{ city, direction string }
+	claims := make(map[slot]link)
+	claim := func(s slot, target string, l link) {
+		previous, found := claims[s]
+		if !found {
+			claims[s] = l
+			return
+		}
+
+		previousTarget := previous.to
+		if previous.from != s.city {
+			previousTarget = previous.from
+		}
+		if previousTarget != target {
+			report(l.line, l.col, SeverityError,
+				"%s=%s contradicts line %d: %s would have both %s and %s to its %s",
+				l.direction, l.to, previous.line, s.city, previousTarget, target, s.direction)
+		}
+	}
+
+	for _, l := range links {
+		claim(slot{l.from, l.direction}, l.to, l)
+		claim(slot{l.to, directions.Opposite(l.direction)}, l.from, l)
+	}
+
+	// Two cities can only be linked by a single road, of a single distance
+	type pair struct{ city, neighbour string }
+	type road struct {
+		direction string // direction from city to neighbour
+		line      int
+		distance  int // first distance given for the road, 0 if none
+		distAt    int // line of the first distance given
+	}
+	roads := make(map[pair]road)
+	for _, l := range links {
+		previous, found := roads[pair{l.from, l.to}]
+		if !found {
+			roads[pair{l.from, l.to}] = road{l.direction, l.line, l.distance, l.line}
+			roads[pair{l.to, l.from}] = road{directions.Opposite(l.direction), l.line, l.distance, l.line}
+			continue
+		}
+		if previous.direction != l.direction {
+			report(l.line, l.col, SeverityError,
+				"%s=%s contradicts line %d: %s and %s would be linked by more than one road",
+				l.direction, l.to, previous.line, l.from, l.to)
+			continue
+		}
+		switch {
+		case l.distance == 0:
+		case previous.distance == 0:
+			previous.distance, previous.distAt = l.distance, l.line
+			roads[pair{l.from, l.to}] = previous
+			roads[pair{l.to, l.from}] = road{directions.Opposite(l.direction), previous.line, l.distance, l.line}
+		case previous.distance != l.distance:
+			report(l.line, l.col, SeverityError,
+				"%s=%s:%d contradicts line %d: the road between %s and %s would be both %d and %d ticks long",
+				l.direction, l.to, l.distance, previous.distAt, l.from, l.to, previous.distance, l.distance)
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Col < diagnostics[j].Col
+	})
+
+	return diagnostics, nil
+}
+
+// ValidateMapFile checks a map file, see ValidateMap
+func ValidateMapFile(filename string) ([]Diagnostic, error) {
+	return ValidateMapFileWithDirections(filename, nil)
+}
+
+// ValidateMapFileWithDirections checks a map file, see ValidateMapWithDirections
+func ValidateMapFileWithDirections(filename string, directions *Directions) ([]Diagnostic, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	return ValidateMapWithDirections(file, filename, directions)
+}
+
+// DefineValidateFlags defines the flags for the validate command
+func (a *App) DefineValidateFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("strict", "s", false, "Fail on warnings as well as errors")
+	cmd.Flags().String("directions", DirectionsCompass, "Direction vocabulary of maps without a @directions header: compass, compass8, layers and <direction>=<opposite> pairs")
+}
+
+// StartValidate validates the map files given as arguments
+// and prints their diagnostics
+func (a *App) StartValidate(cmd *cobra.Command, args []string) error {
+	flags := &flagReader{cmd: cmd}
+	strict := flags.Bool("strict")
+	spec := flags.String("directions")
+	if flags.err != nil {
+		return flags.err
+	}
+
+	directions, err := ParseDirections(spec)
+	if err != nil {
+		return err
+	}
+
+	nErrors, nWarnings := 0, 0
+	for _, filename := range args {
+		diagnostics, err := ValidateMapFileWithDirections(filename, directions)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range diagnostics {
+			fmt.Fprintln(cmd.OutOrStdout(), d)
+			if d.Severity == SeverityError {
+				nErrors++
+			} else {
+				nWarnings++
+			}
+		}
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%d error(s), %d warning(s)\n", nErrors, nWarnings)
+	if nErrors > 0 || (strict && nWarnings > 0) {
+		return fmt.Errorf("map validation failed")
+	}
+	return nil
+}
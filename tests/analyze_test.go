@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A - B - C   D   E - F
+var splitAppCfg = &DummyAppConfig{
+	Map: map[string][]interface{}{
+		"A": {map[string]string{"east": "B"}},
+		"B": {map[string]string{"west": "A"}, map[string]string{"east": "C"}},
+		"C": {map[string]string{"west": "B"}},
+		"D": {},
+		"E": {map[string]string{"east": "F"}},
+		"F": {map[string]string{"west": "E"}},
+	},
+}
+
+func TestAnalyzeMap(t *testing.T) {
+	app := NewDummyApp(splitAppCfg)
+
+	analysis := simulation.AnalyzeMap(app.State.WorldMap)
+	assert.Equal(t, 6, analysis.Cities)
+	assert.Equal(t, 3, analysis.Roads)
+	assert.Equal(t, [][]string{{"A", "B", "C"}, {"E", "F"}, {"D"}}, analysis.Components)
+	assert.Equal(t, map[int]int{0: 1, 1: 4, 2: 1}, analysis.Degrees)
+	assert.Equal(t, 2, analysis.Diameter)
+	assert.Equal(t, []string{"D"}, analysis.Isolated)
+	assert.Equal(t, []string{"A", "C", "E", "F"}, analysis.DeadEnds)
+	assert.Equal(t, []string{"B"}, analysis.ArticulationPoints)
+
+	// A and C are as large, the first one is kept
+	assert.Equal(t, []simulation.CityFall{{City: "B", Unreachable: []string{"C"}}}, analysis.Falls)
+}
+
+func TestAnalyzeMap_SampleMap(t *testing.T) {
+	app := NewEmptyDummyApp()
+	app.Cfg.MapInputFile = "../data/map.txt"
+	require.Nil(t, app.IOController().ReadMapFromFile())
+
+	analysis := simulation.AnalyzeMap(app.State.WorldMap)
+	assert.Len(t, analysis.Components, 1)
+	assert.Equal(t, 16, analysis.Roads)
+	assert.NotContains(t, analysis.ArticulationPoints, "Solitude")
+
+	// Solitude and the north of the map hang off Avaloria
+	for _, fall := range analysis.Falls {
+		if fall.City == "Avaloria" {
+			assert.Equal(t, []string{"Solitude", "Mystica", "Verdantis", "Thundoria", "Wizardwood", "Fireholm"}, fall.Unreachable)
+		}
+	}
+}
+
+func TestWriteMapAnalysis(t *testing.T) {
+	analysis := simulation.AnalyzeMap(NewDummyApp(splitAppCfg).State.WorldMap)
+
+	var buf bytes.Buffer
+	require.Nil(t, simulation.WriteMapAnalysis(&buf, analysis, simulation.FormatJSON))
+	var decoded simulation.MapAnalysis
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, analysis, decoded)
+
+	buf.Reset()
+	require.Nil(t, simulation.WriteMapAnalysis(&buf, analysis, simulation.FormatTable))
+	assert.Contains(t, buf.String(), "Articulation points: B")
+
+	assert.NotNil(t, simulation.WriteMapAnalysis(&buf, analysis, simulation.FormatCSV))
+}
+
+func TestApp_StartAnalyze_Directions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "map.txt")
+	require.Nil(t, os.WriteFile(filename, []byte("A northeast=B\nB\n"), 0644))
+
+	app := simulation.NewApp()
+	cmd := &cobra.Command{}
+	app.DefineAnalyzeFlags(cmd)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	require.Nil(t, cmd.Flags().Set("input", filename))
+	require.Nil(t, cmd.Flags().Set("directions", "compass8"))
+
+	// The roads of the configured vocabulary are linked back
+	require.Nil(t, app.StartAnalyze(cmd, nil))
+	assert.Equal(t, "A", app.State.WorldMap.Cities["B"].Neighbours["southwest"].Name)
+	assert.NotEmpty(t, out.String())
+
+	require.Nil(t, cmd.Flags().Set("directions", "in=out=in"))
+	assert.NotNil(t, app.StartAnalyze(cmd, nil))
+}
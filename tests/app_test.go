@@ -1,10 +1,14 @@
 package tests
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestApp_PopulateMapWithAliens(t *testing.T) {
@@ -27,6 +31,27 @@ func TestApp_PopulateMapWithAliens(t *testing.T) {
 	}
 }
 
+func TestApp_PopulateMapWithAliens_Seeded(t *testing.T) {
+	locations := func(seed int64) map[int]string {
+		app := NewEmptyDummyApp()
+		prefilledApp := NewDummyApp(dummyAppCfg)
+
+		app.State.Aliens = prefilledApp.State.Aliens
+		app.State.WorldMap = prefilledApp.State.WorldMap
+		app.SetSeed(seed)
+
+		app.PopulateMapWithAliens()
+
+		result := make(map[int]string)
+		for id, alien := range app.State.Aliens {
+			result[id] = alien.CurrentCity.Name
+		}
+		return result
+	}
+
+	assert.Equal(t, locations(7), locations(7))
+}
+
 func TestApp_Run(t *testing.T) {
 	t.Run("All aliens get destroyed but part of the world remains", func(t *testing.T) {
 		cfg := &DummyAppConfig{
@@ -61,7 +86,12 @@ func TestApp_Run(t *testing.T) {
 		app := NewDummyApp(cfg)
 		ctrl := app.StateController()
 
-		app.Run()
+		res, err := app.Run(context.Background())
+		require.Nil(t, err)
+		assert.Equal(t, simulation.ReasonAliensDestroyed, res.Reason)
+		assert.Empty(t, res.Survivors)
+		assert.NotEmpty(t, res.DestroyedCities)
+		assert.Greater(t, res.Ticks, 0)
 
 		assert.True(t, ctrl.AreAllAliensDestroyed())
 		assert.False(t, ctrl.IsWorldDestroyed())
@@ -88,7 +118,7 @@ func TestApp_Run(t *testing.T) {
 		app := NewDummyApp(cfg)
 		ctrl := app.StateController()
 
-		app.Run()
+		app.Run(context.Background())
 
 		assert.True(t, ctrl.AreAllAliensDestroyed())
 		assert.True(t, ctrl.IsWorldDestroyed())
@@ -122,7 +152,7 @@ func TestApp_Run(t *testing.T) {
 		app := NewDummyApp(cfg)
 		ctrl := app.StateController()
 
-		app.Run()
+		app.Run(context.Background())
 
 		assert.False(t, ctrl.AreAllAliensDestroyed())
 		assert.False(t, ctrl.IsWorldDestroyed())
@@ -145,7 +175,7 @@ func TestApp_Run(t *testing.T) {
 		app := NewDummyApp(cfg)
 		ctrl := app.StateController()
 
-		app.Run()
+		app.Run(context.Background())
 
 		assert.False(t, ctrl.AreAllAliensDestroyed())
 		assert.False(t, ctrl.IsWorldDestroyed())
@@ -155,14 +185,14 @@ func TestApp_Run(t *testing.T) {
 }
 
 // Test Stop behavior by creating a world with two aliens
-// that will never meet but will keep moving until maximum movement limit is reached (50000)
+// that will never meet but will keep moving until maximum movement limit is reached (10,000,000)
 // Stop will be called after 100ms, prior to maximum movement limit being reached.
 // This test config allows us to ensure that the world won't destroyed, nor the aliens,
 // nor will they be trapped.
 func TestApp_Stop(t *testing.T) {
 	cfg := &DummyAppConfig{
 		AlienCount: 2,
-		MaxMoves:   50000,
+		MaxMoves:   10000000,
 		Map: map[string][]interface{}{
 			"A": {
 				map[string]string{"north": "B"},
@@ -186,7 +216,7 @@ func TestApp_Stop(t *testing.T) {
 	app := NewDummyApp(cfg)
 	ctrl := app.StateController()
 
-	go app.Run()
+	go app.Run(context.Background())
 
 	time.AfterFunc(100*time.Millisecond, func() {
 		app.Stop()
@@ -200,3 +230,147 @@ func TestApp_Stop(t *testing.T) {
 	assert.False(t, ctrl.IsAlienMovementLimitReached())
 	assert.False(t, ctrl.AreRemainingAliensTrapped())
 }
+
+func TestApp_InitWithConfig(t *testing.T) {
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := simulation.NewAppFromConfig(simulation.AppCfg{NumAliens: -1, MaxMoves: 10, MapInputFile: "testdata/test_map.txt"})
+		require.NotNil(t, err)
+
+		_, err = simulation.NewAppFromConfig(simulation.AppCfg{NumAliens: 2, MaxMoves: 0, MapInputFile: "testdata/test_map.txt"})
+		require.NotNil(t, err)
+
+		_, err = simulation.NewAppFromConfig(simulation.AppCfg{NumAliens: 2, MaxMoves: 10})
+		require.NotNil(t, err)
+	})
+
+	t.Run("missing map", func(t *testing.T) {
+		_, err := simulation.NewAppFromConfig(simulation.AppCfg{NumAliens: 2, MaxMoves: 10, MapInputFile: "nofile.txt"})
+		require.NotNil(t, err)
+	})
+
+	t.Run("valid config", func(t *testing.T) {
+		app, err := simulation.NewAppFromConfig(simulation.AppCfg{
+			NumAliens:    3,
+			MaxMoves:     10,
+			MapInputFile: "testdata/test_map.txt",
+			Seed:         5,
+		})
+		require.Nil(t, err)
+
+		assert.Equal(t, int64(5), app.Cfg.Seed)
+		assert.Len(t, app.State.Aliens, 3)
+		assert.Len(t, app.State.WorldMap.Cities, 4)
+		for _, alien := range app.State.Aliens {
+			assert.NotNil(t, alien.CurrentCity)
+		}
+
+		// reinitializing the app does not fail
+		require.Nil(t, app.InitWithConfig(*app.Cfg))
+	})
+}
+
+func TestApp_RunContext(t *testing.T) {
+	// Two aliens that never meet, the run only ends with its context
+	cfg := &DummyAppConfig{
+		AlienCount: 2,
+		MaxMoves:   10000000,
+		Map: map[string][]interface{}{
+			"A": {
+				map[string]string{"north": "B"},
+			},
+			"B": {
+				map[string]string{"south": "A"},
+			},
+			"C": {
+				map[string]string{"west": "D"},
+			},
+			"D": {
+				map[string]string{"east": "C"},
+			},
+		},
+		AlienLocations: map[string][]int{
+			"A": {0},
+			"C": {1},
+		},
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		app := NewDummyApp(cfg)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		res, err := app.Run(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, simulation.ReasonCancelled, res.Reason)
+		assert.Len(t, res.Survivors, 2)
+		assert.Empty(t, res.DestroyedCities)
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		app := NewDummyApp(cfg)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		res, err := app.Run(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, simulation.ReasonDeadlineExceeded, res.Reason)
+	})
+
+	t.Run("cancelled during delay", func(t *testing.T) {
+		app := NewDummyApp(cfg)
+		app.Cfg.UseDelay = true
+		app.Cfg.DelayMS = 60000
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := app.Run(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestApp_RunObserved(t *testing.T) {
+	// Observers read the broadcast states while the run keeps mutating its own,
+	// run with -race to catch any shared pointer
+	app, err := simulation.NewAppFromConfig(simulation.AppCfg{
+		NumAliens:    3,
+		MaxMoves:     200,
+		MapInputFile: "testdata/test_map.txt",
+		UseDelay:     true, // leaves the observer the time to read every state
+		DelayMS:      1,
+		Seed:         1,
+		Strategy:     "avoid",
+		Rules:        simulation.CollisionRules{Threshold: 4}, // aliens never meet, the run lasts
+		Logger:       logger.NewDiscardLogger(),
+	})
+	require.Nil(t, err)
+
+	observed := make(chan struct{})
+	go func() {
+		defer close(observed)
+		for {
+			select {
+			case state := <-app.StateController().ListenForStateUpdates():
+				for _, name := range state.WorldMap.CityNames() {
+					for _, neighbour := range state.WorldMap.Cities[name].Neighbours {
+						_ = neighbour.Name
+					}
+				}
+				for _, alien := range state.Aliens {
+					_ = alien.IsTrapped()
+					_ = len(state.AlienLocations[alien.CurrentCity])
+				}
+			case <-app.Done():
+				return
+			}
+		}
+	}()
+
+	_, err = app.Run(context.Background())
+	require.Nil(t, err)
+	<-observed
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIOController_ReadMapFromFile_Attributes(t *testing.T) {
+	app := NewEmptyDummyApp()
+	app.Cfg.MapInputFile = "testdata/test_attributes_map.txt"
+	require.Nil(t, app.IOController().ReadMapFromFile())
+
+	fort := app.State.WorldMap.Cities["Fort"]
+	assert.Equal(t, 500, fort.Population)
+	assert.Equal(t, 1, fort.Defense)
+	assert.Equal(t, simulation.TerrainHills, fort.Terrain)
+	assert.Equal(t, 2, fort.DefenseLevel())
+	assert.Equal(t, "Field", fort.Neighbours["east"].Name)
+
+	field := app.State.WorldMap.Cities["Field"]
+	assert.Equal(t, 1200, field.Population)
+	assert.Equal(t, 0, field.DefenseLevel())
+
+	// Attributes are written back after the roads
+	var buf bytes.Buffer
+	require.Nil(t, simulation.WriteMap(&buf, app.State.WorldMap))
+	assert.Equal(t, "Fort east=Field @population=500 @defense=1 @terrain=hills\nField west=Fort @population=1200\n", buf.String())
+
+	diagnostics, err := simulation.ValidateMapFile("testdata/test_attributes_map.txt")
+	require.Nil(t, err)
+	assert.Empty(t, diagnostics)
+}
+
+func TestValidateMap_Attributes(t *testing.T) {
+	for input, message := range map[string]string{
+		"A @population=many\n":             `invalid population "many" of A`,
+		"A @defense=-1\n":                  `invalid defense "-1" of A`,
+		"A @terrain=lava\n":                `unknown terrain "lava"`,
+		"A @color=red\n":                   `unknown city attribute "color"`,
+		"A @defense=1 @defense=2\n":        "attribute defense is set more than once for A",
+		"A east=B @terrain=forest\nB\n":    "",
+		"A @terrain=swamp @population=0\n": "",
+	} {
+		diagnostics, err := simulation.ValidateMap(strings.NewReader(input), "map.txt")
+		require.Nil(t, err)
+		if message == "" {
+			assert.Empty(t, diagnostics, input)
+			continue
+		}
+		require.Len(t, diagnostics, 1, input)
+		assert.Contains(t, diagnostics[0].Message, message)
+	}
+}
+
+func TestStateController_ResolveCollisions_Defense(t *testing.T) {
+	app := NewDummyApp(&DummyAppConfig{
+		AlienCount: 4,
+		MaxMoves:   10,
+		Map: map[string][]interface{}{
+			"Fort":  {map[string]string{"east": "Field"}},
+			"Field": {map[string]string{"west": "Fort"}},
+		},
+		AlienLocations: map[string][]int{"Fort": {0, 1}, "Field": {2, 3}},
+	})
+	fort, field := app.State.WorldMap.Cities["Fort"], app.State.WorldMap.Cities["Field"]
+	fort.Defense, fort.Population = 1, 500
+	field.Population = 1200
+
+	require.Nil(t, app.StateController().ResolveCollisions())
+
+	// The defended city absorbs the encounter, the other one falls
+	assert.Contains(t, app.State.WorldMap.Cities, "Fort")
+	assert.Equal(t, 1, app.State.CityDamage["Fort"])
+	assert.Equal(t, []string{"Field"}, app.State.DestroyedCities)
+	assert.Empty(t, app.State.Aliens)
+
+	res := app.Result()
+	assert.Equal(t, 1200, res.PopulationLost)
+	assert.Equal(t, 0, res.DefensesOverwhelmed)
+
+	var buf bytes.Buffer
+	require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatTable))
+	assert.Contains(t, buf.String(), "Population lost:  1200")
+	assert.Contains(t, buf.String(), "Defenses overwhelmed:  0")
+}
+
+func TestStateController_NextCity_Terrain(t *testing.T) {
+	app := NewDummyApp(&DummyAppConfig{
+		AlienCount: 1,
+		MaxMoves:   10,
+		Map: map[string][]interface{}{
+			"Valley": {map[string]string{"north": "Peak"}},
+			"Peak":   {map[string]string{"south": "Valley"}},
+		},
+		AlienLocations: map[string][]int{"Valley": {0}},
+	})
+	app.State.WorldMap.Cities["Peak"].Terrain = simulation.TerrainMountains
+
+	moves := 0
+	for i := 0; i < 1000; i++ {
+		next, err := app.StateController().NextCity(app.State.Aliens[0])
+		require.Nil(t, err)
+		if next != nil {
+			moves++
+		}
+	}
+	assert.InDelta(t, 250, moves, 60)
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBatch(t *testing.T) {
+	cfg := simulation.BatchCfg{
+		Sim: simulation.AppCfg{
+			NumAliens:    3,
+			MaxMoves:     100,
+			MapInputFile: "testdata/test_map.txt",
+			Seed:         1,
+			Logger:       logger.NewDiscardLogger(),
+		},
+		Runs:    20,
+		Workers: 4,
+	}
+
+	t.Run("aggregates every run", func(t *testing.T) {
+		report, err := simulation.RunBatch(context.Background(), cfg)
+		require.Nil(t, err)
+
+		assert.Equal(t, 20, report.Runs)
+		assert.Equal(t, 4, report.Cities)
+		assert.Len(t, report.CityDestruction, 4)
+
+		total := 0
+		for _, count := range report.Reasons {
+			total += count
+		}
+		assert.Equal(t, 20, total)
+
+		assert.GreaterOrEqual(t, report.SurvivingCities.Min, 0.0)
+		assert.LessOrEqual(t, report.SurvivingCities.Max, 100.0)
+		assert.LessOrEqual(t, report.SurvivingCities.Min, report.SurvivingCities.Mean)
+		assert.LessOrEqual(t, report.SurvivingCities.Mean, report.SurvivingCities.Max)
+		for i := 1; i < len(report.CityDestruction); i++ {
+			assert.GreaterOrEqual(t, report.CityDestruction[i-1].Probability, report.CityDestruction[i].Probability)
+		}
+	})
+
+	t.Run("same seed same report", func(t *testing.T) {
+		report1, err := simulation.RunBatch(context.Background(), cfg)
+		require.Nil(t, err)
+
+		cfg2 := cfg
+		cfg2.Workers = 1
+		report2, err := simulation.RunBatch(context.Background(), cfg2)
+		require.Nil(t, err)
+
+		assert.Equal(t, report1, report2)
+	})
+
+	t.Run("invalid runs", func(t *testing.T) {
+		cfg2 := cfg
+		cfg2.Runs = 0
+		_, err := simulation.RunBatch(context.Background(), cfg2)
+		require.NotNil(t, err)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := simulation.RunBatch(ctx, cfg)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("table report", func(t *testing.T) {
+		report, err := simulation.RunBatch(context.Background(), cfg)
+		require.Nil(t, err)
+
+		var buf bytes.Buffer
+		require.Nil(t, simulation.WriteBatchReport(&buf, report, simulation.FormatTable))
+		assert.Contains(t, buf.String(), "Surviving cities (%)")
+		assert.Contains(t, buf.String(), "Termination reasons:")
+	})
+}
+
+func TestApp_StartBatch_UnsupportedFlags(t *testing.T) {
+	for flag, value := range map[string]string{
+		"journal":  "journal.jsonl",
+		"delay":    "true",
+		"delay_ms": "10",
+		"log":      "batch.log",
+		"output":   "map.txt",
+	} {
+		app := simulation.NewApp()
+		cmd := &cobra.Command{}
+		app.DefineBatchFlags(cmd)
+		require.Nil(t, cmd.Flags().Set("input", "testdata/test_map.txt"))
+		require.Nil(t, cmd.Flags().Set(flag, value))
+
+		err := app.StartBatch(cmd, nil)
+		require.NotNil(t, err, flag)
+		assert.Contains(t, err.Error(), "--"+flag)
+	}
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveUntil receives events until one of the given tick, failing after a second
+func receiveUntil(t *testing.T, events <-chan simulation.Event, tick int) []int {
+	var ticks []int
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			require.True(t, ok, "channel closed")
+			ticks = append(ticks, e.Tick)
+			if e.Tick == tick {
+				return ticks
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out", "received ticks %v", ticks)
+		}
+	}
+}
+
+func TestEventBus_Block(t *testing.T) {
+	bus := simulation.NewEventBus()
+	events, cancel, err := bus.Subscribe(simulation.SubscribeOptions{Policy: simulation.PolicyBlock})
+	require.Nil(t, err)
+	defer cancel()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			bus.Publish(simulation.Event{Tick: i})
+		}
+	}()
+
+	ticks := receiveUntil(t, events, 99)
+	require.Len(t, ticks, 100)
+	for i, tick := range ticks {
+		assert.Equal(t, i, tick)
+	}
+}
+
+func TestEventBus_LatestOnly(t *testing.T) {
+	bus := simulation.NewEventBus()
+	events, cancel, err := bus.Subscribe(simulation.SubscribeOptions{Policy: simulation.PolicyLatestOnly})
+	require.Nil(t, err)
+	defer cancel()
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(simulation.Event{Tick: i})
+	}
+
+	// at most the event in flight and the latest one
+	ticks := receiveUntil(t, events, 9)
+	assert.LessOrEqual(t, len(ticks), 2)
+}
+
+func TestEventBus_RingBuffer(t *testing.T) {
+	bus := simulation.NewEventBus()
+	events, cancel, err := bus.Subscribe(simulation.SubscribeOptions{Policy: simulation.PolicyRingBuffer, Buffer: 3})
+	require.Nil(t, err)
+	defer cancel()
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(simulation.Event{Tick: i})
+	}
+
+	// at most the event in flight and the last three ones
+	ticks := receiveUntil(t, events, 9)
+	assert.LessOrEqual(t, len(ticks), 4)
+	assert.Equal(t, []int{7, 8, 9}, ticks[len(ticks)-3:])
+}
+
+func TestEventBus_Types(t *testing.T) {
+	bus := simulation.NewEventBus()
+	events, cancel, err := bus.Subscribe(simulation.SubscribeOptions{Types: []simulation.EventType{simulation.EventCityDestroyed}})
+	require.Nil(t, err)
+	defer cancel()
+
+	bus.Publish(simulation.Event{Tick: 1, Type: simulation.EventAlienMoved})
+	bus.Publish(simulation.Event{Tick: 2, Type: simulation.EventCityDestroyed})
+
+	assert.Equal(t, []int{2}, receiveUntil(t, events, 2))
+}
+
+func TestEventBus_InvalidOptions(t *testing.T) {
+	bus := simulation.NewEventBus()
+	for _, opts := range []simulation.SubscribeOptions{
+		{Policy: "drop-everything"},
+		{Policy: simulation.PolicyBlock, Buffer: -1},
+	} {
+		assert.NotNil(t, opts.Validate())
+		_, _, err := bus.Subscribe(opts)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, 0, bus.Subscribers())
+}
+
+func TestEventBus_Cancel(t *testing.T) {
+	bus := simulation.NewEventBus()
+	blocked, cancelBlocked, err := bus.Subscribe(simulation.SubscribeOptions{Policy: simulation.PolicyBlock})
+	require.Nil(t, err)
+	ring, cancelRing, err := bus.Subscribe(simulation.SubscribeOptions{})
+	require.Nil(t, err)
+	assert.Equal(t, 2, bus.Subscribers())
+
+	// Cancelling releases a publisher waiting for the subscriber
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(simulation.Event{Tick: 1})
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelBlocked()
+	<-published
+
+	cancelRing()
+	cancelRing()
+	assert.Equal(t, 0, bus.Subscribers())
+
+	for _, events := range []<-chan simulation.Event{blocked, ring} {
+		for range events {
+			// drain until closed
+		}
+	}
+}
+
+func TestApp_Subscribe(t *testing.T) {
+	app := simulation.NewApp()
+
+	// Subscriptions made before the initialization receive the landings
+	first, cancelFirst, err := app.Subscribe(simulation.SubscribeOptions{Policy: simulation.PolicyBlock})
+	require.Nil(t, err)
+	defer cancelFirst()
+	second, cancelSecond, err := app.Subscribe(simulation.SubscribeOptions{Policy: simulation.PolicyBlock})
+	require.Nil(t, err)
+	defer cancelSecond()
+
+	collect := func(events <-chan simulation.Event) <-chan []simulation.Event {
+		collected := make(chan []simulation.Event)
+		go func() {
+			var all []simulation.Event
+			for e := range events {
+				all = append(all, e)
+				if e.Type == simulation.EventSimulationEnded {
+					break
+				}
+			}
+			collected <- all
+		}()
+		return collected
+	}
+	firstEvents, secondEvents := collect(first), collect(second)
+
+	require.Nil(t, app.InitWithConfig(simulation.AppCfg{
+		NumAliens:    4,
+		MaxMoves:     50,
+		MapInputFile: "testdata/test_map.txt",
+		Seed:         1,
+		Logger:       logger.NewDiscardLogger(),
+	}))
+	res, err := app.Run(context.Background())
+	require.Nil(t, err)
+
+	events := <-firstEvents
+	assert.Equal(t, events, <-secondEvents)
+	require.NotEmpty(t, events)
+	assert.Equal(t, simulation.EventAlienLanded, events[0].Type)
+	assert.Equal(t, string(res.Reason), events[len(events)-1].Reason)
+
+	ticksEnded := 0
+	for _, e := range events {
+		if e.Type == simulation.EventTickEnded {
+			ticksEnded++
+		}
+	}
+	assert.Equal(t, res.Ticks, ticksEnded)
+}
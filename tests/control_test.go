@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// endlessAppCfg is a simulation whose aliens never meet, it only ends when stopped
+var endlessAppCfg = simulation.AppCfg{
+	NumAliens:    3,
+	MaxMoves:     10000000,
+	MapInputFile: "testdata/test_map.txt",
+	Seed:         1,
+	Rules:        simulation.CollisionRules{Threshold: 4},
+	Logger:       logger.NewDiscardLogger(),
+}
+
+func TestApp_PauseStepResume(t *testing.T) {
+	app, err := simulation.NewAppFromConfig(endlessAppCfg)
+	require.Nil(t, err)
+
+	ticks, cancel, err := app.Subscribe(simulation.SubscribeOptions{
+		Policy: simulation.PolicyBlock,
+		Buffer: 1000,
+		Types:  []simulation.EventType{simulation.EventTickEnded},
+	})
+	require.Nil(t, err)
+	defer cancel()
+
+	app.Pause()
+	ended := make(chan simulation.Result)
+	go func() {
+		res, _ := app.Run(context.Background())
+		ended <- res
+	}()
+
+	// Paused, nothing happens
+	select {
+	case e := <-ticks:
+		require.FailNow(t, "ticked while paused", "%v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// unless a single tick is requested
+	app.RequestStep()
+	e := <-ticks
+	assert.Equal(t, 1, e.Tick)
+	select {
+	case e := <-ticks:
+		require.FailNow(t, "ticked more than once", "%v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Resumed, the simulation goes on
+	app.Resume()
+	e = <-ticks
+	assert.Equal(t, 2, e.Tick)
+
+	// stop listening first, the blocked simulation would not see the stop otherwise
+	cancel()
+	app.Stop()
+	res := <-ended
+	assert.Equal(t, simulation.ReasonStopped, res.Reason)
+}
+
+func TestApp_SetDelay(t *testing.T) {
+	app, err := simulation.NewAppFromConfig(endlessAppCfg)
+	require.Nil(t, err)
+	assert.Equal(t, 0, app.Delay())
+
+	app.SetDelay(20)
+	assert.Equal(t, 20, app.Delay())
+	app.SetDelay(-1)
+	assert.Equal(t, 0, app.Delay())
+
+	cfg := endlessAppCfg
+	cfg.UseDelay = true
+	cfg.DelayMS = 30
+	app, err = simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	assert.Equal(t, 30, app.Delay())
+}
+
+func TestApp_Restart(t *testing.T) {
+	app, err := simulation.NewAppFromConfig(endlessAppCfg)
+	require.Nil(t, err)
+
+	// Restarting a running simulation stops it first
+	ended := make(chan simulation.Result)
+	go func() {
+		res, _ := app.Run(context.Background())
+		ended <- res
+	}()
+	require.Eventually(t, app.IsRunning, time.Second, time.Millisecond)
+
+	require.Nil(t, app.Restart(7))
+	assert.Equal(t, simulation.ReasonStopped, (<-ended).Reason)
+	assert.Equal(t, int64(7), app.Cfg.Seed)
+	assert.Equal(t, 0, app.State.Tick)
+	assert.False(t, app.IsStopped())
+
+	// The aliens landed like in a new simulation with the same seed
+	cfg := endlessAppCfg
+	cfg.Seed = 7
+	fresh, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	require.Len(t, app.State.Aliens, 3)
+	for id, alien := range fresh.State.Aliens {
+		assert.Equal(t, alien.CurrentCity.Name, app.State.Aliens[id].CurrentCity.Name)
+	}
+
+	// and the restarted simulation runs again
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err := app.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(7), res.Seed)
+	assert.NotZero(t, res.Ticks)
+}
+
+func TestApp_Restart_Journal(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	cfg := endlessAppCfg
+	cfg.MaxMoves = 20
+	cfg.JournalFile = journal
+	app, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	first, err := app.Run(context.Background())
+	require.Nil(t, err)
+
+	// The restarted run journals next to the first journal
+	require.Nil(t, app.Restart(7))
+	assert.Equal(t, filepath.Join(filepath.Dir(journal), "journal.1.jsonl"), app.Cfg.JournalFile)
+	_, err = app.Run(context.Background())
+	require.Nil(t, err)
+	require.Nil(t, app.Restart(8))
+	assert.Equal(t, filepath.Join(filepath.Dir(journal), "journal.2.jsonl"), app.Cfg.JournalFile)
+
+	// and the first journal still replays the first run
+	events, err := simulation.ReadJournalFile(journal)
+	require.Nil(t, err)
+	replayed := NewEmptyDummyApp()
+	replayed.Cfg.MapInputFile = "testdata/test_map.txt"
+	require.Nil(t, replayed.IOController().ReadMapFromFile())
+	_, err = replayed.Replay(events, -1)
+	require.Nil(t, err)
+	assert.Equal(t, simulation.EventSimulationEnded, events[len(events)-1].Type)
+	assert.Equal(t, first.Ticks, replayed.State.Tick)
+	require.Len(t, replayed.State.Aliens, len(first.Survivors))
+	for _, survivor := range first.Survivors {
+		assert.Equal(t, survivor.City, replayed.State.Aliens[survivor.ID].CurrentCity.Name)
+	}
+}
+
+func TestApp_Start_Interrupted(t *testing.T) {
+	dir := t.TempDir()
+	app := simulation.NewApp()
+	cmd := &cobra.Command{}
+	app.DefineFlags(cmd)
+	for name, value := range map[string]string{
+		"input":    "testdata/test_map.txt",
+		"output":   filepath.Join(dir, "map.txt"),
+		"log":      filepath.Join(dir, "stdout.log"),
+		"snapshot": filepath.Join(dir, "snapshot.json"),
+		"format":   simulation.FormatJSON,
+	} {
+		require.Nil(t, cmd.Flags().Set(name, value))
+	}
+
+	// An interrupted simulation ends normally, with its result and snapshot saved
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd.SetContext(ctx)
+	require.Nil(t, app.Start(cmd, nil))
+	assert.Equal(t, simulation.ReasonCancelled, app.Result().Reason)
+	assert.FileExists(t, filepath.Join(dir, "map.txt"))
+	assert.FileExists(t, filepath.Join(dir, "snapshot.json"))
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defendedApp is a line of cities A - B from west to east
+// with the given aliens and number of defenders in A
+func defendedApp(aliens []int, defenders int) *simulation.App {
+	app := NewDummyApp(&DummyAppConfig{
+		AlienCount: len(aliens),
+		MaxMoves:   10,
+		Map: map[string][]interface{}{
+			"A": {map[string]string{"east": "B"}},
+			"B": {map[string]string{"west": "A"}},
+		},
+		AlienLocations: map[string][]int{"A": aliens},
+	})
+
+	city := app.State.WorldMap.Cities["A"]
+	app.State.Defenders = make(simulation.DefenderSet)
+	app.State.DefenderLocations = map[*simulation.City]simulation.DefenderSet{city: {}}
+	for id := 0; id < defenders; id++ {
+		defender := &simulation.Defender{ID: id, CurrentCity: city}
+		app.State.Defenders[id] = defender
+		app.State.DefenderLocations[city][id] = defender
+	}
+	return app
+}
+
+func TestStateController_ResolveCollisions_Defenders(t *testing.T) {
+	t.Run("lone alien", func(t *testing.T) {
+		app := defendedApp([]int{0}, 1)
+		events, cancel, err := app.Subscribe(simulation.SubscribeOptions{Buffer: 10})
+		require.Nil(t, err)
+		defer cancel()
+
+		require.Nil(t, app.StateController().ResolveCollisions())
+		assert.Empty(t, app.State.Aliens)
+		assert.Len(t, app.State.Defenders, 1)
+		assert.Equal(t, simulation.EventAlienRepelled, (<-events).Type)
+	})
+
+	t.Run("outnumbered", func(t *testing.T) {
+		app := defendedApp([]int{0}, 0)
+		require.Nil(t, app.StateController().ResolveCollisions())
+		assert.Len(t, app.State.Aliens, 1)
+	})
+
+	t.Run("encounter", func(t *testing.T) {
+		app := defendedApp([]int{0, 1}, 2)
+
+		// A defender falls holding the city
+		require.Nil(t, app.StateController().ResolveCollisions())
+		assert.Contains(t, app.State.WorldMap.Cities, "A")
+		assert.Zero(t, app.State.CityDamage["A"])
+		assert.Empty(t, app.State.Aliens)
+		assert.Equal(t, []int{1}, app.State.Defenders.IDs())
+	})
+
+	t.Run("fallen city", func(t *testing.T) {
+		app := defendedApp([]int{0, 1}, 0)
+		app.State.Defenders[0] = &simulation.Defender{ID: 0, CurrentCity: app.State.WorldMap.Cities["B"]}
+		require.Nil(t, app.StateController().DestroyCity("B"))
+		assert.Empty(t, app.State.Defenders)
+	})
+}
+
+func TestApp_Run_Defenders(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	cfg := simulation.AppCfg{
+		NumAliens:        4,
+		MaxMoves:         20,
+		MapInputFile:     "testdata/test_map.txt",
+		Seed:             11,
+		JournalFile:      journal,
+		NumDefenders:     3,
+		DefenderCities:   []string{"A", "D"},
+		DefenderStrategy: "seek",
+		Logger:           logger.NewDiscardLogger(),
+	}
+	app, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	require.Len(t, app.State.Defenders, 3)
+	assert.Equal(t, "A", app.State.Defenders[0].CurrentCity.Name)
+	assert.Equal(t, "D", app.State.Defenders[1].CurrentCity.Name)
+	assert.Equal(t, "A", app.State.Defenders[2].CurrentCity.Name)
+
+	res, err := app.Run(context.Background())
+	require.Nil(t, err)
+	require.Len(t, res.Defenders, len(app.State.Defenders))
+
+	var buf bytes.Buffer
+	require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatTable))
+	assert.Contains(t, buf.String(), "Remaining Defenders:")
+
+	// The journal replays the defenders
+	events, err := simulation.ReadJournalFile(journal)
+	require.Nil(t, err)
+
+	// The defenders actually patrolled the map
+	moves := 0
+	for _, e := range events {
+		if e.Type == simulation.EventDefenderMoved {
+			moves++
+		}
+	}
+	assert.NotZero(t, moves)
+
+	replayed := NewEmptyDummyApp()
+	replayed.Cfg.MapInputFile = "testdata/test_map.txt"
+	require.Nil(t, replayed.IOController().ReadMapFromFile())
+	_, err = replayed.Replay(events, -1)
+	require.Nil(t, err)
+	require.Equal(t, app.State.Defenders.IDs(), replayed.State.Defenders.IDs())
+	for id, defender := range app.State.Defenders {
+		assert.Equal(t, defender.CurrentCity.Name, replayed.State.Defenders[id].CurrentCity.Name)
+		assert.Equal(t, defender.Moved, replayed.State.Defenders[id].Moved)
+	}
+
+	// and so do the snapshots
+	var snapshot bytes.Buffer
+	require.Nil(t, app.Snapshot(&snapshot))
+	restored := simulation.NewApp()
+	require.Nil(t, restored.Restore(&snapshot))
+	assert.Equal(t, app.State.Defenders.IDs(), restored.State.Defenders.IDs())
+
+	// Unknown cities are refused
+	cfg.DefenderCities = []string{"Atlantis"}
+	cfg.JournalFile = ""
+	_, err = simulation.NewAppFromConfig(cfg)
+	assert.NotNil(t, err)
+
+	// and so are the defenders that could never move
+	cfg.DefenderCities = nil
+	cfg.DefenderStrategy = "weighted:north=0,south=0,east=0,west=0"
+	_, err = simulation.NewAppFromConfig(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestApp_Run_Defenders_Patrol(t *testing.T) {
+	for _, strategy := range []string{"uniform", "seek", "avoid", "lazy:0.5"} {
+		cfg := simulation.AppCfg{
+			NumAliens:        2,
+			MaxMoves:         50,
+			MapInputFile:     "testdata/test_map.txt",
+			Seed:             3,
+			NumDefenders:     2,
+			DefenderStrategy: strategy,
+			Rules:            simulation.CollisionRules{Threshold: 4},
+			Logger:           logger.NewDiscardLogger(),
+		}
+		app, err := simulation.NewAppFromConfig(cfg)
+		require.Nil(t, err)
+
+		moved := 0
+		_, err = app.RunUntil(func(report simulation.TickReport) bool {
+			for _, e := range report.Events {
+				if e.Type == simulation.EventDefenderMoved {
+					moved++
+				}
+			}
+			return report.Tick == 20
+		})
+		require.Nil(t, err)
+		assert.NotZero(t, moved, strategy)
+	}
+}
+
+func TestJournal_Defender(t *testing.T) {
+	var buf bytes.Buffer
+	journal := simulation.NewJournal(&buf)
+	require.Nil(t, journal.Append(simulation.Event{Type: simulation.EventDefenderLanded, Defender: 0, City: "A"}))
+
+	// Defender 0 is recorded like alien 0
+	assert.Contains(t, buf.String(), `"defender":0`)
+	events, err := simulation.ReadJournal(&buf)
+	require.Nil(t, err)
+	assert.Equal(t, 0, events[0].Defender)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirections(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		directions, err := simulation.ParseDirections("")
+		require.Nil(t, err)
+		assert.True(t, directions.IsDefault())
+		assert.Equal(t, []string{"north", "south", "east", "west"}, directions.Names())
+		assert.Equal(t, "", directions.Opposite("up"))
+	})
+
+	t.Run("presets and pairs", func(t *testing.T) {
+		directions, err := simulation.ParseDirections("compass8, layers portal=portal")
+		require.Nil(t, err)
+		assert.False(t, directions.IsDefault())
+		assert.Equal(t, "southwest", directions.Opposite("northeast"))
+		assert.Equal(t, "up", directions.Opposite("down"))
+		assert.Equal(t, "portal", directions.Opposite("portal"))
+		assert.True(t, directions.Has("southeast"))
+		assert.False(t, directions.Has("in"))
+
+		// String parses back to the same vocabulary
+		reparsed, err := simulation.ParseDirections(directions.String())
+		require.Nil(t, err)
+		assert.Equal(t, directions.Names(), reparsed.Names())
+
+		sorted := []string{"portal", "zzz", "up", "southwest", "north", "west"}
+		directions.Sort(sorted)
+		assert.Equal(t, []string{"north", "west", "southwest", "up", "portal", "zzz"}, sorted)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, spec := range []string{"compass up=north", "in=out=in", "=out", "in:1=out", "hexagonal"} {
+			_, err := simulation.ParseDirections(spec)
+			assert.NotNil(t, err, spec)
+		}
+	})
+}
+
+func TestIOController_ReadMapFromFile_Directions(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		app := NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "testdata/test_directions_map.txt"
+		require.Nil(t, app.IOController().ReadMapFromFile())
+
+		cities := app.State.WorldMap.Cities
+		require.Len(t, cities, 3)
+		assert.Equal(t, "Ground", cities["Tower"].Neighbours["southwest"].Name)
+		assert.Equal(t, "Ground", cities["Sky"].Neighbours["down"].Name)
+		assert.Equal(t, "Tower", cities["Sky"].Neighbours["portal"].Name)
+
+		// The header is written back
+		var buf bytes.Buffer
+		require.Nil(t, simulation.WriteMap(&buf, app.State.WorldMap))
+		assert.Equal(t, "@directions compass8 layers portal=portal\n"+
+			"Ground northeast=Tower up=Sky\n"+
+			"Tower southwest=Ground portal=Sky\n"+
+			"Sky down=Ground portal=Tower\n", buf.String())
+
+		diagnostics, err := simulation.ValidateMapFile("testdata/test_directions_map.txt")
+		require.Nil(t, err)
+		assert.Empty(t, diagnostics)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		app := NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "testdata/test_unknown_direction_map.txt"
+		app.Cfg.Directions = "compass layers"
+		require.Nil(t, app.IOController().ReadMapFromFile())
+		assert.Equal(t, "A", app.State.WorldMap.Cities["B"].Neighbours["down"].Name)
+
+		app = NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "testdata/test_unknown_direction_map.txt"
+		app.Cfg.Directions = "layers"
+		app.Cfg.Strict = true
+		require.Nil(t, app.IOController().ReadMapFromFile())
+
+		// The compass does not know the direction, the configured vocabulary does
+		diagnostics, err := simulation.ValidateMapFile("testdata/test_unknown_direction_map.txt")
+		require.Nil(t, err)
+		assert.NotEmpty(t, diagnostics)
+		directions, err := simulation.ParseDirections("layers")
+		require.Nil(t, err)
+		diagnostics, err = simulation.ValidateMapFileWithDirections("testdata/test_unknown_direction_map.txt", directions)
+		require.Nil(t, err)
+		assert.Empty(t, diagnostics)
+	})
+
+	t.Run("misplaced header", func(t *testing.T) {
+		diagnostics, err := simulation.ValidateMap(strings.NewReader("A up=B\n@directions layers\nB\n"), "map.txt")
+		require.Nil(t, err)
+		require.Len(t, diagnostics, 2)
+		assert.Equal(t, 1, diagnostics[0].Line)
+		assert.Equal(t, `unknown direction "up"`, diagnostics[0].Message)
+		assert.Equal(t, 2, diagnostics[1].Line)
+		assert.Contains(t, diagnostics[1].Message, "must come before the cities")
+
+		diagnostics, err = simulation.ValidateMap(strings.NewReader("@directions compass up=north\nA\n"), "map.txt")
+		require.Nil(t, err)
+		require.Len(t, diagnostics, 1)
+		assert.Contains(t, diagnostics[0].Message, "opposite of both")
+	})
+}
+
+func TestApp_Result_Directions(t *testing.T) {
+	app, err := simulation.NewAppFromConfig(simulation.AppCfg{
+		MaxMoves:     10,
+		MapInputFile: "testdata/test_directions_map.txt",
+		Strategy:     "lazy:0.5:weighted:portal=1,southwest=2",
+	})
+	require.Nil(t, err)
+
+	// Neighbours and weights are printed in the order of the map vocabulary
+	res := app.Result()
+	assert.Equal(t, "compass8 layers portal=portal", res.Directions)
+	require.Len(t, res.RemainingCities, 3)
+	for _, city := range res.RemainingCities {
+		if city.Name == "Tower" {
+			assert.Equal(t, "southwest=Ground portal=Sky", city.NeighboursStringWithDirections(res.Vocabulary()))
+		}
+	}
+
+	var buf bytes.Buffer
+	require.Nil(t, app.IOController().WriteResult(&buf, res, "csv"))
+	assert.Contains(t, buf.String(), "city,Tower,southwest=Ground portal=Sky,\n")
+	assert.Equal(t, "lazy:0.5:weighted:southwest=2,portal=1", app.StateController().Strategy().String())
+}
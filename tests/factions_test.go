@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFactions(t *testing.T) {
+	shares, err := simulation.ParseFactions("red:3, blue:2")
+	require.Nil(t, err)
+	assert.Equal(t, []simulation.FactionShare{{Name: "red", Weight: 3}, {Name: "blue", Weight: 2}}, shares)
+
+	shares, err = simulation.ParseFactions("")
+	require.Nil(t, err)
+	assert.Empty(t, shares)
+
+	for _, spec := range []string{"red", "red:x", "red:-1", "red:1,red:2", ":1", "red:0,blue:0"} {
+		_, err := simulation.ParseFactions(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestAssignFactions(t *testing.T) {
+	for spec, expected := range map[string][]string{
+		// counts
+		"red:3,blue:2": {"red", "red", "red", "blue", "blue"},
+		// ratios, the rounding favours the first declared factions
+		"red:0.5,blue:0.5": {"red", "red", "red", "blue", "blue"},
+		"red:1,blue:4":     {"red", "blue", "blue", "blue", "blue"},
+	} {
+		shares, err := simulation.ParseFactions(spec)
+		require.Nil(t, err)
+		assert.Equal(t, expected, simulation.AssignFactions(shares, 5), spec)
+	}
+}
+
+func TestStateController_ResolveCollisions_Factions(t *testing.T) {
+	newApp := func(factions map[int]string, rules simulation.CollisionRules) *simulation.App {
+		ids := make([]int, len(factions))
+		for i := range ids {
+			ids[i] = i
+		}
+		app := NewDummyApp(&DummyAppConfig{
+			AlienCount: len(factions),
+			MaxMoves:   10,
+			Map: map[string][]interface{}{
+				"A": {map[string]string{"east": "B"}},
+				"B": {map[string]string{"west": "A"}},
+			},
+			AlienLocations: map[string][]int{"A": ids},
+			Rules:          rules,
+		})
+		for id, faction := range factions {
+			app.State.Aliens[id].Faction = faction
+		}
+		return app
+	}
+
+	t.Run("allies", func(t *testing.T) {
+		app := newApp(map[int]string{0: "red", 1: "red"}, simulation.CollisionRules{})
+
+		// The allies destroy the city together and flee
+		require.Nil(t, app.StateController().ResolveCollisions())
+		assert.Equal(t, []string{"A"}, app.State.DestroyedCities)
+		assert.Equal(t, []int{0, 1}, app.State.Aliens.IDs())
+		assert.Equal(t, "B", app.State.Aliens[0].CurrentCity.Name)
+	})
+
+	t.Run("enemies", func(t *testing.T) {
+		app := newApp(map[int]string{0: "red", 1: "blue", 2: "red"}, simulation.CollisionRules{CityHitPoints: 2})
+
+		// The largest faction wins the battle
+		require.Nil(t, app.StateController().ResolveCollisions())
+		assert.Equal(t, []int{0, 2}, app.State.Aliens.IDs())
+		assert.Equal(t, 1, app.State.CityDamage["A"])
+	})
+}
+
+func TestApp_Run_Factions(t *testing.T) {
+	cfg := simulation.AppCfg{
+		NumAliens:    6,
+		MaxMoves:     20,
+		MapInputFile: "testdata/test_map.txt",
+		Seed:         5,
+		Factions:     "red:2,blue:1",
+		Logger:       logger.NewDiscardLogger(),
+	}
+	app, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]int{"blue": 2, "red": 4}, app.State.FactionLanded)
+
+	res, err := app.Run(context.Background())
+	require.Nil(t, err)
+	require.Len(t, res.Factions, 2)
+	assert.Equal(t, "blue", res.Factions[0].Name)
+	assert.Equal(t, 2, res.Factions[0].Landed)
+	assert.Equal(t, len(res.Survivors), res.Factions[0].Survivors+res.Factions[1].Survivors)
+
+	var buf bytes.Buffer
+	require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatTable))
+	assert.Contains(t, buf.String(), "Factions:")
+
+	// Factions survive the snapshots
+	var snapshot bytes.Buffer
+	require.Nil(t, app.Snapshot(&snapshot))
+	restored := simulation.NewApp()
+	require.Nil(t, restored.Restore(&snapshot))
+	assert.Equal(t, app.State.FactionLanded, restored.State.FactionLanded)
+	for id, alien := range app.State.Aliens {
+		assert.Equal(t, alien.Faction, restored.State.Aliens[id].Faction)
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCfg_Validate(t *testing.T) {
+	valid := simulation.GenerateCfg{Kind: simulation.MapRandom, Size: 10, Density: 0.5, Continents: 2}
+	assert.Nil(t, valid.Validate())
+
+	for name, cfg := range map[string]simulation.GenerateCfg{
+		"unknown kind":   {Kind: "spiral", Size: 10, Density: 0.5},
+		"size":           {Kind: simulation.MapTree, Size: 0, Density: 0.5},
+		"density":        {Kind: simulation.MapGrid, Size: 10, Density: 1.5},
+		"odd ring":       {Kind: simulation.MapRing, Size: 7, Density: 0.5},
+		"continents":     {Kind: simulation.MapContinents, Size: 10, Density: 0.5, Continents: 0},
+		"too many lands": {Kind: simulation.MapContinents, Size: 2, Density: 0.5, Continents: 3},
+	} {
+		assert.NotNil(t, cfg.Validate(), name)
+	}
+}
+
+func TestGenerateMap(t *testing.T) {
+	for _, kind := range []simulation.MapKind{simulation.MapGrid, simulation.MapRandom, simulation.MapTree, simulation.MapRing, simulation.MapContinents} {
+		t.Run(string(kind), func(t *testing.T) {
+			cfg := simulation.GenerateCfg{Kind: kind, Size: 30, Density: 0.6, Seed: 5, Continents: 3}
+			worldMap, err := simulation.GenerateMap(cfg)
+			require.Nil(t, err)
+			assert.Len(t, worldMap.Cities, 30)
+
+			var written bytes.Buffer
+			require.Nil(t, simulation.WriteMap(&written, worldMap))
+
+			// The same configuration generates the same map
+			again, err := simulation.GenerateMap(cfg)
+			require.Nil(t, err)
+			var rewritten bytes.Buffer
+			require.Nil(t, simulation.WriteMap(&rewritten, again))
+			assert.Equal(t, written.String(), rewritten.String())
+
+			// The map is valid and round-trips through the map file format
+			filename := filepath.Join(t.TempDir(), "map.txt")
+			require.Nil(t, os.WriteFile(filename, written.Bytes(), 0644))
+			diagnostics, err := simulation.ValidateMapFile(filename)
+			require.Nil(t, err)
+			assert.Empty(t, diagnostics)
+
+			app := NewEmptyDummyApp()
+			app.Cfg.MapInputFile = filename
+			require.Nil(t, app.IOController().ReadMapFromFile())
+			var read bytes.Buffer
+			require.Nil(t, simulation.WriteMap(&read, app.State.WorldMap))
+			assert.Equal(t, written.String(), read.String())
+
+			// and can be drawn on a grid
+			_, err = simulation.ComputeLayout(worldMap)
+			assert.Nil(t, err)
+
+			analysis := simulation.AnalyzeMap(worldMap)
+			switch kind {
+			case simulation.MapRandom, simulation.MapContinents:
+				assert.Len(t, analysis.Components, 1)
+			case simulation.MapTree:
+				assert.Len(t, analysis.Components, 1)
+				assert.Equal(t, 29, analysis.Roads)
+			case simulation.MapRing:
+				assert.Equal(t, map[int]int{2: 30}, analysis.Degrees)
+				assert.Empty(t, analysis.ArticulationPoints)
+			}
+		})
+	}
+}
+
+func TestApp_StartGenerate_Seed(t *testing.T) {
+	generate := func(seed string) (string, string) {
+		app := simulation.NewApp()
+		cmd := &cobra.Command{}
+		app.DefineGenerateFlags(cmd)
+		var out, errOut bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&errOut)
+		require.Nil(t, cmd.Flags().Set("seed", seed))
+		require.Nil(t, app.StartGenerate(cmd, nil))
+		return out.String(), errOut.String()
+	}
+
+	// The random seed is reported apart from the map written to stdout
+	written, report := generate("0")
+	match := regexp.MustCompile(`with seed (-?\d+)`).FindStringSubmatch(report)
+	require.Len(t, match, 2, report)
+	_, err := strconv.ParseInt(match[1], 10, 64)
+	require.Nil(t, err)
+
+	// and generates the same map again
+	again, _ := generate(match[1])
+	assert.Equal(t, written, again)
+}
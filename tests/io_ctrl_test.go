@@ -2,11 +2,18 @@ package tests
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	simulation "github.com/derrandz/xtinvasion/pkg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 // test_map.txt contains
@@ -101,3 +108,106 @@ func TestIOController_WriteMapToFile(t *testing.T) {
 		assert.True(t, line == "A north=B" || line == "B south=A")
 	}
 }
+
+func TestIOController_WriteResult(t *testing.T) {
+	app := NewDummyApp(dummyAppCfg)
+	app.Cfg.Seed = 42
+	require.Nil(t, app.StateController().DestroyCity("A"))
+	res := app.Result()
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatJSON))
+
+		var decoded simulation.Result
+		require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, res, decoded)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatYAML))
+
+		var decoded simulation.Result
+		require.Nil(t, yaml.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, res, decoded)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatCSV))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		require.Nil(t, err)
+		assert.Equal(t, []string{"record", "name", "value", "detail"}, records[0])
+		assert.Contains(t, records, []string{"summary", "seed", "42", ""})
+		assert.Contains(t, records, []string{"city", "B", "east=D", ""})
+		assert.Contains(t, records, []string{"alien", "3", "D", "0"})
+		assert.Contains(t, records, []string{"destroyed", "A", "1", ""})
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatTable))
+		assert.Contains(t, buf.String(), "Remaining Cities:")
+		assert.Contains(t, buf.String(), "Seed:  42")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NotNil(t, app.IOController().WriteResult(&buf, res, "xml"))
+	})
+}
+
+// test_roundtrip_map.txt contains unordered directions, extra spaces
+// and a city without neighbours
+func TestIOController_WriteMapToFile_RoundTrip(t *testing.T) {
+	app := NewEmptyDummyApp()
+	app.Cfg.MapInputFile = "testdata/test_roundtrip_map.txt"
+	require.Nil(t, app.IOController().ReadMapFromFile())
+
+	var buf bytes.Buffer
+	require.Nil(t, simulation.WriteMap(&buf, app.State.WorldMap))
+	assert.Equal(t, strings.Join([]string{
+		"Solitude north=Avaloria",
+		"Avaloria north=Mystica south=Solitude east=Verdantis",
+		"Mystica south=Avaloria east=Wizardwood",
+		"Wizardwood south=Verdantis west=Mystica",
+		"Verdantis north=Wizardwood west=Avaloria",
+		"Lonely",
+	}, "\n")+"\n", buf.String())
+
+	// Read the written map back
+	app.Cfg.MapOutputFile = filepath.Join(t.TempDir(), "map.txt")
+	require.Nil(t, app.IOController().WriteMapToFile())
+
+	reread := NewEmptyDummyApp()
+	reread.Cfg.MapInputFile = app.Cfg.MapOutputFile
+	require.Nil(t, reread.IOController().ReadMapFromFile())
+
+	require.Equal(t, app.State.WorldMap.OrderedCityNames(), reread.State.WorldMap.OrderedCityNames())
+	for name, city := range app.State.WorldMap.Cities {
+		rereadCity := reread.State.WorldMap.Cities[name]
+		require.Equal(t, len(city.Neighbours), len(rereadCity.Neighbours), name)
+		for direction, neighbour := range city.Neighbours {
+			assert.Equal(t, neighbour.Name, rereadCity.Neighbours[direction].Name, "%s %s", name, direction)
+		}
+	}
+
+	// Writing it again yields the same file
+	var rewritten bytes.Buffer
+	require.Nil(t, simulation.WriteMap(&rewritten, reread.State.WorldMap))
+	assert.Equal(t, buf.String(), rewritten.String())
+
+	// Destroyed cities are left out, their neighbours lose the link
+	require.Nil(t, app.StateController().DestroyCity("Avaloria"))
+	buf.Reset()
+	require.Nil(t, simulation.WriteMap(&buf, app.State.WorldMap))
+	assert.Equal(t, strings.Join([]string{
+		"Solitude",
+		"Mystica east=Wizardwood",
+		"Wizardwood south=Verdantis west=Mystica",
+		"Verdantis north=Wizardwood",
+		"Lonely",
+	}, "\n")+"\n", buf.String())
+}
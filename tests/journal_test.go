@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal_ReadJournal(t *testing.T) {
+	var buf bytes.Buffer
+	journal := simulation.NewJournal(&buf)
+
+	require.Nil(t, journal.Append(simulation.Event{Type: simulation.EventAlienLanded, Alien: 0, City: "A"}))
+	require.Nil(t, journal.Append(simulation.Event{Tick: 1, Type: simulation.EventAlienMoved, Alien: 0, From: "A", City: "B"}))
+
+	events, err := simulation.ReadJournal(&buf)
+	require.Nil(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, simulation.EventAlienMoved, events[1].Type)
+	assert.Equal(t, "B", events[1].City)
+
+	_, err = simulation.ReadJournal(bytes.NewBufferString("not json\n"))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line 1")
+}
+
+func TestJournal_Replay(t *testing.T) {
+	// Record a whole simulation
+	var buf bytes.Buffer
+	app := NewDummyApp(&DummyAppConfig{MaxMoves: 50})
+	app.Cfg.MapInputFile = "testdata/test_map.txt"
+	require.Nil(t, app.IOController().ReadMapFromFile())
+	for i := 0; i < 4; i++ {
+		app.State.Aliens[i] = &simulation.Alien{ID: i}
+	}
+	app.SetJournal(simulation.NewJournal(&buf))
+	app.PopulateMapWithAliens()
+	app.Run(context.Background())
+
+	events, err := simulation.ReadJournal(&buf)
+	require.Nil(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, simulation.EventAlienLanded, events[0].Type)
+	assert.Equal(t, simulation.EventSimulationEnded, events[len(events)-1].Type)
+
+	t.Run("whole journal", func(t *testing.T) {
+		replayed := NewEmptyDummyApp()
+		replayed.Cfg.MapInputFile = "testdata/test_map.txt"
+		require.Nil(t, replayed.IOController().ReadMapFromFile())
+
+		applied, err := replayed.Replay(events, -1)
+		require.Nil(t, err)
+		assert.Equal(t, len(events), applied)
+
+		assert.Equal(t, app.State.Tick, replayed.State.Tick)
+		assert.ElementsMatch(t, app.State.WorldMap.CityNames(), replayed.State.WorldMap.CityNames())
+		require.Equal(t, len(app.State.Aliens), len(replayed.State.Aliens))
+		for id, alien := range app.State.Aliens {
+			assert.Equal(t, alien.CurrentCity.Name, replayed.State.Aliens[id].CurrentCity.Name)
+			assert.Equal(t, alien.Moved, replayed.State.Aliens[id].Moved)
+		}
+	})
+
+	t.Run("until landing", func(t *testing.T) {
+		replayed := NewEmptyDummyApp()
+		replayed.Cfg.MapInputFile = "testdata/test_map.txt"
+		require.Nil(t, replayed.IOController().ReadMapFromFile())
+
+		_, err := replayed.Replay(events, 0)
+		require.Nil(t, err)
+
+		assert.Equal(t, 0, replayed.State.Tick)
+		assert.Len(t, replayed.State.Aliens, 4)
+		for _, alien := range replayed.State.Aliens {
+			assert.Equal(t, 0, alien.Moved)
+		}
+	})
+}
+
+func TestApp_StartReplay_Format(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal.jsonl")
+	require.Nil(t, os.WriteFile(journal, []byte(`{"tick":0,"type":"AlienLanded","alien":0,"city":"A"}`+"\n"), 0644))
+
+	app := simulation.NewApp()
+	cmd := &cobra.Command{}
+	app.DefineReplayFlags(cmd)
+	output := filepath.Join(dir, "map.txt")
+	for name, value := range map[string]string{
+		"journal": journal,
+		"input":   "testdata/test_map.txt",
+		"output":  output,
+		"log":     filepath.Join(dir, "replay.log"),
+		"format":  "xml",
+	} {
+		require.Nil(t, cmd.Flags().Set(name, value))
+	}
+
+	// The format is refused before anything is written
+	err := app.StartReplay(cmd, nil)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "xml")
+	assert.NoFileExists(t, output)
+}
+
+func TestApp_StartReplay_Reason(t *testing.T) {
+	dir := t.TempDir()
+	run := func(name string, ctx context.Context) (string, simulation.Result) {
+		journal := filepath.Join(dir, name)
+		app, err := simulation.NewAppFromConfig(simulation.AppCfg{
+			NumAliens:    4,
+			MaxMoves:     20,
+			MapInputFile: "testdata/test_map.txt",
+			Seed:         5,
+			JournalFile:  journal,
+			Logger:       logger.NewDiscardLogger(),
+		})
+		require.Nil(t, err)
+		res, _ := app.Run(ctx)
+		return journal, res
+	}
+	replay := func(journal, until string) simulation.Result {
+		app := simulation.NewApp()
+		cmd := &cobra.Command{}
+		app.DefineReplayFlags(cmd)
+		for name, value := range map[string]string{
+			"journal": journal,
+			"output":  filepath.Join(dir, "replay_map.txt"),
+			"log":     filepath.Join(dir, "replay.log"),
+			"format":  simulation.FormatJSON,
+			"until":   until,
+		} {
+			require.Nil(t, cmd.Flags().Set(name, value))
+		}
+		require.Nil(t, app.StartReplay(cmd, nil))
+		return app.Result()
+	}
+
+	// The recorded reason is replayed
+	journal, res := run("full.jsonl", context.Background())
+	replayed := replay(journal, "-1")
+	assert.Equal(t, res.Reason, replayed.Reason)
+	assert.Equal(t, res.Ticks, replayed.Ticks)
+
+	// a replay stopping before the end is in progress
+	assert.Equal(t, simulation.ReasonInProgress, replay(journal, "1").Reason)
+
+	// and a cancelled run stays cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	journal, _ = run("cancelled.jsonl", ctx)
+	assert.Equal(t, simulation.ReasonCancelled, replay(journal, "-1").Reason)
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeLayout(t *testing.T) {
+	t.Run("square", func(t *testing.T) {
+		// A - B
+		// |   |
+		// C - D
+		app := NewDummyApp(&DummyAppConfig{
+			Map: map[string][]interface{}{
+				"A": {map[string]string{"east": "B"}, map[string]string{"south": "C"}},
+				"B": {map[string]string{"west": "A"}, map[string]string{"south": "D"}},
+				"C": {map[string]string{"north": "A"}, map[string]string{"east": "D"}},
+				"D": {map[string]string{"north": "B"}, map[string]string{"west": "C"}},
+			},
+		})
+
+		layout, err := simulation.ComputeLayout(app.State.WorldMap)
+		require.Nil(t, err)
+		assert.Equal(t, 2, layout.Width)
+		assert.Equal(t, 2, layout.Height)
+		assert.Equal(t, [][]string{{"A", "B"}, {"C", "D"}}, layout.Grid())
+
+		name, found := layout.At(simulation.GridPoint{X: 1, Y: 1})
+		assert.True(t, found)
+		assert.Equal(t, "D", name)
+	})
+
+	t.Run("shared cell", func(t *testing.T) {
+		// B is east of A and C is south of A, but D is both south of B and east of C:
+		// the roads fit on a grid only if cities are moved apart
+		app := NewDummyApp(&DummyAppConfig{
+			Map: map[string][]interface{}{
+				"A": {map[string]string{"east": "B"}, map[string]string{"south": "C"}},
+				"B": {map[string]string{"south": "D"}},
+				"C": {map[string]string{"east": "E"}},
+				"E": {},
+				"D": {},
+			},
+		})
+
+		layout, err := simulation.ComputeLayout(app.State.WorldMap)
+		require.Nil(t, err)
+		assert.Len(t, layout.Positions, 5)
+		assert.Equal(t, layout.Positions["B"].X, layout.Positions["D"].X)
+		assert.Equal(t, layout.Positions["C"].Y, layout.Positions["E"].Y)
+		assert.NotEqual(t, layout.Positions["D"], layout.Positions["E"])
+	})
+
+	t.Run("inconsistent", func(t *testing.T) {
+		// A - B - C - A: C cannot be both east and west of A
+		app := NewDummyApp(&DummyAppConfig{
+			Map: map[string][]interface{}{
+				"A": {map[string]string{"east": "B"}},
+				"B": {map[string]string{"east": "C"}},
+				"C": {map[string]string{"east": "A"}},
+			},
+		})
+
+		_, err := simulation.ComputeLayout(app.State.WorldMap)
+		var layoutErr *simulation.LayoutError
+		require.ErrorAs(t, err, &layoutErr)
+		require.Len(t, layoutErr.Problems, 1)
+		assert.Contains(t, layoutErr.Problems[0], "A, B, C")
+	})
+
+	t.Run("same column", func(t *testing.T) {
+		// C is east of A but B is north of both, putting them in the same column
+		app := NewDummyApp(&DummyAppConfig{
+			Map: map[string][]interface{}{
+				"A": {map[string]string{"north": "B"}, map[string]string{"east": "C"}},
+				"B": {},
+				"C": {map[string]string{"north": "B"}},
+			},
+		})
+
+		_, err := simulation.ComputeLayout(app.State.WorldMap)
+		var layoutErr *simulation.LayoutError
+		assert.ErrorAs(t, err, &layoutErr)
+	})
+
+	t.Run("sample map", func(t *testing.T) {
+		// Avaloria is south of Mystica while Mystica is west of Avaloria
+		app := NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "../data/map.txt"
+		require.Nil(t, app.IOController().ReadMapFromFile())
+
+		_, err := simulation.ComputeLayout(app.State.WorldMap)
+		var layoutErr *simulation.LayoutError
+		require.ErrorAs(t, err, &layoutErr)
+		assert.Contains(t, layoutErr.Problems, "Mystica and Avaloria would be in the same row and apart along it")
+	})
+
+	t.Run("test map", func(t *testing.T) {
+		// B is north of C while both are west of D
+		app := NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "testdata/test_map.txt"
+		require.Nil(t, app.IOController().ReadMapFromFile())
+
+		_, err := simulation.ComputeLayout(app.State.WorldMap)
+		var layoutErr *simulation.LayoutError
+		assert.ErrorAs(t, err, &layoutErr)
+	})
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateController_ReproduceAliens(t *testing.T) {
+	app := NewDummyApp(&DummyAppConfig{
+		AlienCount: 2,
+		MaxMoves:   10,
+		Map: map[string][]interface{}{
+			"A": {map[string]string{"east": "B"}},
+			"B": {map[string]string{"west": "A"}},
+		},
+		AlienLocations: map[string][]int{"A": {0}, "B": {1}},
+	})
+	app.Cfg.ReproduceAfter = 3
+	app.State.Aliens[0].Moved = 3
+	app.State.Aliens[0].Faction = "red"
+	app.State.Aliens[1].Moved = 2
+
+	// Only the aliens having moved enough give birth, in their city
+	app.StateController().ReproduceAliens()
+	assert.Equal(t, []int{0, 1, 2}, app.State.Aliens.IDs())
+	offspring := app.State.Aliens[2]
+	assert.Equal(t, "A", offspring.CurrentCity.Name)
+	assert.Equal(t, "red", offspring.Faction)
+	assert.Equal(t, 0, offspring.Moved)
+	assert.Len(t, app.State.AlienLocations[offspring.CurrentCity], 2)
+	assert.Equal(t, 1, app.State.Births)
+
+	// once
+	app.StateController().ReproduceAliens()
+	assert.Len(t, app.State.Aliens, 3)
+}
+
+func TestApp_Run_Waves(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	cfg := simulation.AppCfg{
+		NumAliens:    0,
+		MaxMoves:     20,
+		MapInputFile: "testdata/test_map.txt",
+		Seed:         3,
+		JournalFile:  journal,
+		Waves:        2,
+		WaveSize:     3,
+		WaveEvery:    4,
+		WaveCities:   []string{"A", "D"},
+		Factions:     "red:2,blue:1",
+		Logger:       logger.NewDiscardLogger(),
+	}
+
+	// Without aliens, the pending waves keep the simulation going
+	app, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	report, err := app.Step()
+	require.Nil(t, err)
+	assert.False(t, report.Ended())
+
+	report, err = app.RunUntil(func(report simulation.TickReport) bool { return report.Tick == 4 })
+	require.Nil(t, err)
+	require.False(t, report.Ended())
+	assert.Equal(t, 1, app.State.WavesLanded)
+	assert.Equal(t, map[string]int{"red": 2, "blue": 1}, app.State.FactionLanded)
+	assert.Equal(t, simulation.EventAlienLanded, report.Events[0].Type)
+	assert.Equal(t, "A", report.Events[0].City)
+	assert.Equal(t, "D", report.Events[1].City)
+
+	res, err := app.Run(context.Background())
+	require.Nil(t, err)
+	assert.Equal(t, 2, res.Waves)
+	assert.Equal(t, 6, app.State.NextAlienID)
+	assert.GreaterOrEqual(t, res.Ticks, 8)
+
+	var buf bytes.Buffer
+	require.Nil(t, app.IOController().WriteResult(&buf, res, simulation.FormatTable))
+	assert.Contains(t, buf.String(), "Reinforcement waves:  2")
+
+	// The journal replays the reinforcements
+	events, err := simulation.ReadJournalFile(journal)
+	require.Nil(t, err)
+	replayed := NewEmptyDummyApp()
+	replayed.Cfg.MapInputFile = "testdata/test_map.txt"
+	require.Nil(t, replayed.IOController().ReadMapFromFile())
+	_, err = replayed.Replay(events, -1)
+	require.Nil(t, err)
+	assert.Equal(t, app.State.Aliens.IDs(), replayed.State.Aliens.IDs())
+	assert.Equal(t, app.State.FactionLanded, replayed.State.FactionLanded)
+
+	// Unknown cities are refused
+	cfg.WaveCities = []string{"Atlantis"}
+	cfg.JournalFile = ""
+	_, err = simulation.NewAppFromConfig(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestApp_Run_Waves_MovementLimit(t *testing.T) {
+	cfg := simulation.AppCfg{
+		NumAliens:    2,
+		MaxMoves:     1,
+		MapInputFile: "testdata/test_map.txt",
+		Seed:         3,
+		Waves:        1,
+		WaveSize:     1,
+		WaveEvery:    1000,
+		Rules:        simulation.CollisionRules{Threshold: 4},
+		Logger:       logger.NewDiscardLogger(),
+	}
+	app, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+
+	// The movement limit ends the simulation even though a wave is pending
+	res, err := app.Run(context.Background())
+	require.Nil(t, err)
+	assert.Equal(t, simulation.ReasonMovementLimit, res.Reason)
+	assert.Equal(t, 0, res.Waves)
+	assert.Less(t, res.Ticks, 1000)
+}
+
+func TestApp_Run_Reproduction(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	cfg := simulation.AppCfg{
+		NumAliens:      2,
+		MaxMoves:       10,
+		MapInputFile:   "testdata/test_map.txt",
+		Seed:           9,
+		JournalFile:    journal,
+		ReproduceAfter: 2,
+		Rules:          simulation.CollisionRules{Threshold: 4},
+		Logger:         logger.NewDiscardLogger(),
+	}
+	app, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+
+	res, err := app.Run(context.Background())
+	require.Nil(t, err)
+	require.Greater(t, res.Births, 0)
+	assert.Equal(t, 2+res.Births, app.State.NextAlienID)
+
+	// The journal replays the births
+	events, err := simulation.ReadJournalFile(journal)
+	require.Nil(t, err)
+	replayed := NewEmptyDummyApp()
+	replayed.Cfg.MapInputFile = "testdata/test_map.txt"
+	require.Nil(t, replayed.IOController().ReadMapFromFile())
+	_, err = replayed.Replay(events, -1)
+	require.Nil(t, err)
+	assert.Equal(t, app.State.Aliens.IDs(), replayed.State.Aliens.IDs())
+	assert.Equal(t, res.Births, replayed.State.Births)
+
+	// and so do the snapshots
+	var snapshot bytes.Buffer
+	require.Nil(t, app.Snapshot(&snapshot))
+	restored := simulation.NewApp()
+	require.Nil(t, restored.Restore(&snapshot))
+	assert.Equal(t, app.State.NextAlienID, restored.State.NextAlienID)
+	assert.Equal(t, res.Births, restored.State.Births)
+	for id, alien := range app.State.Aliens {
+		assert.Equal(t, alien.Reproduced, restored.State.Aliens[id].Reproduced)
+	}
+}
+
+func TestAppCfg_Validate_Waves(t *testing.T) {
+	for name, cfg := range map[string]simulation.AppCfg{
+		"negative waves": {Waves: -1},
+		"empty waves":    {Waves: 1, WaveSize: 0, WaveEvery: 1},
+		"no interval":    {Waves: 1, WaveSize: 1, WaveEvery: 0},
+		"reproduction":   {ReproduceAfter: -1},
+	} {
+		cfg.MaxMoves = 1
+		cfg.MapInputFile = "map.txt"
+		assert.NotNil(t, cfg.Validate(), name)
+	}
+}
+
+func TestApp_StartReplay_Waves(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal.jsonl")
+	app, err := simulation.NewAppFromConfig(simulation.AppCfg{
+		NumAliens:      2,
+		MaxMoves:       20,
+		MapInputFile:   "testdata/test_map.txt",
+		Seed:           4,
+		JournalFile:    journal,
+		Waves:          2,
+		WaveSize:       2,
+		WaveEvery:      2,
+		ReproduceAfter: 3,
+		Logger:         logger.NewDiscardLogger(),
+	})
+	require.Nil(t, err)
+	res, err := app.Run(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 2, res.Waves)
+
+	replayed := simulation.NewApp()
+	cmd := &cobra.Command{}
+	replayed.DefineReplayFlags(cmd)
+	for name, value := range map[string]string{
+		"journal": journal,
+		"output":  filepath.Join(dir, "replay_map.txt"),
+		"log":     filepath.Join(dir, "replay.log"),
+		"format":  simulation.FormatJSON,
+	} {
+		require.Nil(t, cmd.Flags().Set(name, value))
+	}
+	require.Nil(t, replayed.StartReplay(cmd, nil))
+
+	// The replay reports the same result as the run
+	replayedRes := replayed.Result()
+	assert.Equal(t, res.Waves, replayedRes.Waves)
+	assert.Equal(t, res.Births, replayedRes.Births)
+	assert.Equal(t, res.Reason, replayedRes.Reason)
+	assert.Equal(t, res.Survivors, replayedRes.Survivors)
+	assert.Equal(t, res.DestroyedCities, replayedRes.DestroyedCities)
+}
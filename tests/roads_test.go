@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRoadsApp reads the roads test map, where the road between A and B takes 3 ticks,
+// and lands the aliens in the given cities
+func newRoadsApp(t *testing.T, locations map[int]string) *simulation.App {
+	app := NewDummyApp(&DummyAppConfig{MaxMoves: 50})
+	app.Cfg.MapInputFile = "testdata/test_roads_map.txt"
+	require.Nil(t, app.IOController().ReadMapFromFile())
+
+	for id, name := range locations {
+		city := app.State.WorldMap.Cities[name]
+		app.State.Aliens[id] = &simulation.Alien{ID: id, CurrentCity: city}
+		if app.State.AlienLocations[city] == nil {
+			app.State.AlienLocations[city] = simulation.AlienSet{}
+		}
+		app.State.AlienLocations[city][id] = app.State.Aliens[id]
+	}
+	return app
+}
+
+func TestIOController_ReadMapFromFile_Distances(t *testing.T) {
+	app := newRoadsApp(t, nil)
+
+	cities := app.State.WorldMap.Cities
+	assert.Equal(t, 3, cities["A"].Distance("east"))
+	assert.Equal(t, 3, cities["B"].Distance("west"))
+	assert.Equal(t, 3, cities["B"].DistanceTo(cities["A"]))
+	assert.Equal(t, 1, cities["B"].Distance("east"))
+
+	// Distances are written back on both sides of the road
+	var buf bytes.Buffer
+	require.Nil(t, simulation.WriteMap(&buf, app.State.WorldMap))
+	assert.Equal(t, "A east=B:3\nB south=D east=C west=A:3\nC west=B\nD north=B\n", buf.String())
+
+	diagnostics, err := simulation.ValidateMapFile("testdata/test_roads_map.txt")
+	require.Nil(t, err)
+	assert.Empty(t, diagnostics)
+}
+
+func TestValidateMap_Distances(t *testing.T) {
+	for input, message := range map[string]string{
+		"A east=B:0\nB\n":             `invalid distance "0" of the road to B`,
+		"A east=B:far\nB\n":           `invalid distance "far" of the road to B`,
+		"A east=B:3\nB west=A:2\n":    "would be both 3 and 2 ticks long",
+		"A east=B\nB west=A:2\nC\n":   "",
+		"A east=B:2\nB west=A:2\nC\n": "",
+	} {
+		diagnostics, err := simulation.ValidateMap(strings.NewReader(input), "map.txt")
+		require.Nil(t, err)
+		if message == "" {
+			assert.Empty(t, diagnostics, input)
+			continue
+		}
+		require.Len(t, diagnostics, 1, input)
+		assert.Contains(t, diagnostics[0].Message, message)
+	}
+}
+
+func TestApp_Step_Transit(t *testing.T) {
+	app := newRoadsApp(t, map[int]string{0: "A"})
+	var buf bytes.Buffer
+	app.SetJournal(simulation.NewJournal(&buf))
+
+	// The alien takes the long road to B
+	report, err := app.Step()
+	require.Nil(t, err)
+	assert.Equal(t, 1, report.Moves)
+	alien := app.State.Aliens[0]
+	assert.True(t, alien.InTransit())
+	assert.False(t, alien.IsTrapped())
+	assert.Equal(t, "B", alien.CurrentCity.Name)
+	assert.Equal(t, 3, alien.Moved)
+	assert.Empty(t, app.State.AlienLocations[app.State.WorldMap.Cities["B"]])
+
+	// and is still on the road, also once restored from a snapshot
+	_, err = app.Step()
+	require.Nil(t, err)
+	assert.True(t, alien.InTransit())
+
+	var snapshot bytes.Buffer
+	require.Nil(t, app.Snapshot(&snapshot))
+	restored := simulation.NewApp()
+	require.Nil(t, restored.Restore(&snapshot))
+	assert.Equal(t, alien.Arrival, restored.State.Aliens[0].Arrival)
+	assert.Empty(t, restored.State.AlienLocations[restored.State.WorldMap.Cities["B"]])
+	assert.Equal(t, 3, restored.State.WorldMap.Cities["B"].Distance("west"))
+
+	// until it arrives, spending the tick
+	report, err = app.Step()
+	require.Nil(t, err)
+	assert.Equal(t, simulation.EventAlienArrived, report.Events[len(report.Events)-2].Type)
+	assert.Equal(t, 0, report.Moves)
+	assert.False(t, alien.InTransit())
+	assert.Contains(t, app.State.AlienLocations[app.State.WorldMap.Cities["B"]], 0)
+
+	// The replay puts the alien back on the road
+	events, err := simulation.ReadJournal(&buf)
+	require.Nil(t, err)
+	replayed := NewEmptyDummyApp()
+	replayed.Cfg.MapInputFile = "testdata/test_roads_map.txt"
+	require.Nil(t, replayed.IOController().ReadMapFromFile())
+	_, err = replayed.Replay(append([]simulation.Event{{Type: simulation.EventAlienLanded, Alien: 0, City: "A"}}, events...), 2)
+	require.Nil(t, err)
+	assert.True(t, replayed.State.Aliens[0].InTransit())
+	assert.Equal(t, 3, replayed.State.Aliens[0].Moved)
+
+	_, err = replayed.Replay(events, -1)
+	require.Nil(t, err)
+	assert.False(t, replayed.State.Aliens[0].InTransit())
+}
+
+func TestApp_Step_TransitToDestroyedCity(t *testing.T) {
+	app := newRoadsApp(t, map[int]string{0: "A", 1: "C", 2: "D"})
+
+	// Aliens 1 and 2 reach B while alien 0 is on the road
+	_, err := app.Step()
+	require.Nil(t, err)
+	assert.True(t, app.State.Aliens[0].InTransit())
+
+	// B falls, and alien 0 with it
+	report, err := app.Step()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"B"}, report.DestroyedCities)
+	assert.Equal(t, []int{1, 2, 0}, report.DestroyedAliens)
+	assert.Empty(t, app.State.Aliens)
+	assert.Empty(t, app.State.WorldMap.Cities["A"].Distances)
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crowdedLineAppCfg is the line of lineAppCfg with aliens 0, 1 and 2 in C
+// and alien 3 in E, under the given rules
+func crowdedLineAppCfg(rules simulation.CollisionRules) *DummyAppConfig {
+	cfg := *lineAppCfg
+	cfg.AlienCount = 4
+	cfg.AlienLocations = map[string][]int{
+		"C": {0, 1, 2},
+		"E": {3},
+	}
+	cfg.Rules = rules
+	return &cfg
+}
+
+func TestCollisionRules_Validate(t *testing.T) {
+	assert.Nil(t, simulation.CollisionRules{}.Validate())
+	assert.Nil(t, simulation.CollisionRules{Threshold: 3, SurvivalChance: 0.5, Mode: simulation.CollisionFight, CityHitPoints: 2}.Validate())
+
+	for _, rules := range []simulation.CollisionRules{
+		{Threshold: 1},
+		{SurvivalChance: 1.5},
+		{Mode: "tickle"},
+		{CityHitPoints: -1},
+	} {
+		assert.NotNil(t, rules.Validate(), "%+v", rules)
+	}
+}
+
+func TestResolveCollisions_Default(t *testing.T) {
+	app := NewDummyApp(crowdedLineAppCfg(simulation.CollisionRules{}))
+
+	require.Nil(t, app.StateController().ResolveCollisions())
+	assert.Equal(t, []string{"C"}, app.State.DestroyedCities)
+	assert.Equal(t, []int{3}, app.State.Aliens.IDs())
+}
+
+func TestResolveCollisions_Threshold(t *testing.T) {
+	app := NewDummyApp(crowdedLineAppCfg(simulation.CollisionRules{Threshold: 4}))
+
+	require.Nil(t, app.StateController().ResolveCollisions())
+	assert.Empty(t, app.State.DestroyedCities)
+	assert.Len(t, app.State.Aliens, 4)
+}
+
+func TestResolveCollisions_CityHitPoints(t *testing.T) {
+	app := NewDummyApp(crowdedLineAppCfg(simulation.CollisionRules{CityHitPoints: 2}))
+
+	// The first encounter only damages the city
+	require.Nil(t, app.StateController().ResolveCollisions())
+	assert.Empty(t, app.State.DestroyedCities)
+	assert.Equal(t, 1, app.State.CityDamage["C"])
+	assert.Equal(t, []int{3}, app.State.Aliens.IDs())
+	assert.Contains(t, app.State.WorldMap.Cities, "C")
+
+	// The second one destroys it
+	app.State.Aliens[4] = &simulation.Alien{ID: 4}
+	app.State.Aliens[5] = &simulation.Alien{ID: 5}
+	c := app.State.WorldMap.Cities["C"]
+	app.State.AlienLocations[c] = simulation.AlienSet{}
+	for _, id := range []int{4, 5} {
+		app.State.Aliens[id].CurrentCity = c
+		app.State.AlienLocations[c][id] = app.State.Aliens[id]
+	}
+
+	require.Nil(t, app.StateController().ResolveCollisions())
+	assert.Equal(t, []string{"C"}, app.State.DestroyedCities)
+	assert.Equal(t, []int{3}, app.State.Aliens.IDs())
+}
+
+func TestResolveCollisions_Fight(t *testing.T) {
+	app := NewDummyApp(crowdedLineAppCfg(simulation.CollisionRules{Mode: simulation.CollisionFight}))
+
+	require.Nil(t, app.StateController().ResolveCollisions())
+	assert.Equal(t, []string{"C"}, app.State.DestroyedCities)
+	require.Len(t, app.State.Aliens, 2)
+
+	// The winner fled to B or D before C fell
+	for _, id := range app.State.Aliens.IDs() {
+		if id == 3 {
+			continue
+		}
+		alien := app.State.Aliens[id]
+		assert.Contains(t, []string{"B", "D"}, alien.CurrentCity.Name)
+		assert.Equal(t, 1, alien.Moved)
+	}
+}
+
+func TestResolveCollisions_SurvivalChance(t *testing.T) {
+	app := NewDummyApp(crowdedLineAppCfg(simulation.CollisionRules{SurvivalChance: 1, CityHitPoints: 2}))
+
+	// Every alien survives the hit on the city
+	require.Nil(t, app.StateController().ResolveCollisions())
+	assert.Empty(t, app.State.DestroyedCities)
+	assert.Len(t, app.State.Aliens, 4)
+
+	// and flees it when it falls
+	require.Nil(t, app.StateController().ResolveCollisions())
+	assert.Equal(t, []string{"C"}, app.State.DestroyedCities)
+	assert.Len(t, app.State.Aliens, 4)
+	for _, id := range []int{0, 1, 2} {
+		assert.Contains(t, []string{"B", "D"}, app.State.Aliens[id].CurrentCity.Name)
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/derrandz/xtinvasion/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotAppCfg(seed int64, maxMoves int) simulation.AppCfg {
+	return simulation.AppCfg{
+		NumAliens:       3,
+		MaxMoves:        maxMoves,
+		MapInputFile:    "testdata/test_map.txt",
+		Seed:            seed,
+		Strategy:        "lazy:0.5",
+		AlienStrategies: []string{"0=seek"},
+		Logger:          logger.NewDiscardLogger(),
+	}
+}
+
+func TestApp_SnapshotRestore(t *testing.T) {
+	app, err := simulation.NewAppFromConfig(snapshotAppCfg(3, 5))
+	require.Nil(t, err)
+	app.Run(context.Background())
+
+	var snapshot bytes.Buffer
+	require.Nil(t, app.Snapshot(&snapshot))
+
+	restored := simulation.NewApp()
+	require.Nil(t, restored.Restore(bytes.NewReader(snapshot.Bytes())))
+
+	assert.Equal(t, app.State.Tick, restored.State.Tick)
+	assert.Equal(t, app.State.DestroyedCities, restored.State.DestroyedCities)
+	assert.Equal(t, app.State.WorldMap.OrderedCityNames(), restored.State.WorldMap.OrderedCityNames())
+	assert.Equal(t, app.Rand().Int63(), restored.Rand().Int63())
+	require.Equal(t, app.State.Aliens.IDs(), restored.State.Aliens.IDs())
+	for id, alien := range app.State.Aliens {
+		assert.Equal(t, alien.CurrentCity.Name, restored.State.Aliens[id].CurrentCity.Name)
+		assert.Equal(t, alien.Moved, restored.State.Aliens[id].Moved)
+		assert.Contains(t, restored.State.AlienLocations[restored.State.Aliens[id].CurrentCity], id)
+	}
+	if alien, found := restored.State.Aliens[0]; found {
+		assert.Equal(t, simulation.SeekStrategy{}, alien.Strategy)
+	}
+
+	assert.NotNil(t, restored.Restore(bytes.NewBufferString(`{"version": 0}`)))
+	assert.NotNil(t, restored.Restore(bytes.NewBufferString(`{"version": 1, "aliens": [{"id": 0, "city": "Nowhere"}]}`)))
+}
+
+func TestApp_Resume(t *testing.T) {
+	// Find a run interrupted by the movement limit before its end
+	var (
+		seed   int64
+		paused *simulation.App
+	)
+	for seed = 1; seed < 100; seed++ {
+		app, err := simulation.NewAppFromConfig(snapshotAppCfg(seed, 3))
+		require.Nil(t, err)
+		if res, _ := app.Run(context.Background()); res.Reason == simulation.ReasonMovementLimit {
+			paused = app
+			break
+		}
+	}
+	require.NotNil(t, paused, "no run reached the movement limit")
+
+	filename := filepath.Join(t.TempDir(), "snapshot.json")
+	require.Nil(t, paused.SnapshotToFile(filename))
+
+	// The whole run goes through the paused state, the resumed run must end the same way
+	whole, err := simulation.NewAppFromConfig(snapshotAppCfg(seed, 20))
+	require.Nil(t, err)
+	expected, err := whole.Run(context.Background())
+	require.Nil(t, err)
+
+	cfg := snapshotAppCfg(0, 20)
+	cfg.ResumeFile = filename
+	resumed, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	assert.Equal(t, seed, resumed.Cfg.Seed)
+
+	actual, err := resumed.Run(context.Background())
+	require.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestApp_RequestSnapshot(t *testing.T) {
+	app, err := simulation.NewAppFromConfig(snapshotAppCfg(1, 10000000))
+	require.Nil(t, err)
+
+	go app.Run(context.Background())
+
+	var snapshot bytes.Buffer
+	require.Nil(t, app.RequestSnapshot(&snapshot))
+	app.Stop()
+	app.Wait()
+
+	restored := simulation.NewApp()
+	require.Nil(t, restored.Restore(&snapshot))
+
+	// once the run has ended, the snapshot is taken right away
+	snapshot.Reset()
+	require.Nil(t, app.RequestSnapshot(&snapshot))
+	assert.NotZero(t, snapshot.Len())
+}
+
+func TestAppCfg_Validate_Resume(t *testing.T) {
+	cfg := snapshotAppCfg(1, 10)
+	cfg.ResumeFile = "snapshot.json"
+	cfg.JournalFile = "journal.jsonl"
+	assert.NotNil(t, cfg.Validate())
+}
@@ -328,3 +328,37 @@ func TestStateCtrl_BroadcastStateChanges(t *testing.T) {
 	assert.True(t, len(state.WorldMap.Cities) == 3)
 	assert.True(t, len(state.AlienLocations) == 3)
 }
+
+func TestStateCtrl_CopyState(t *testing.T) {
+	app := NewDummyApp(dummyAppCfg)
+	ctrl := app.StateController()
+
+	state := ctrl.CopyState()
+
+	// The copy shares no pointer with the state
+	for name, city := range app.State.WorldMap.Cities {
+		copied := state.WorldMap.Cities[name]
+		require.NotNil(t, copied)
+		assert.NotSame(t, city, copied)
+		for direction, neighbour := range copied.Neighbours {
+			assert.Same(t, state.WorldMap.Cities[neighbour.Name], neighbour)
+			assert.Equal(t, city.Neighbours[direction].Name, neighbour.Name)
+		}
+	}
+	for id, alien := range state.Aliens {
+		assert.NotSame(t, app.State.Aliens[id], alien)
+		assert.Same(t, state.WorldMap.Cities[alien.CurrentCity.Name], alien.CurrentCity)
+		assert.Same(t, alien, state.AlienLocations[alien.CurrentCity][id])
+	}
+
+	// Mutating the state leaves the copy untouched
+	require.Nil(t, ctrl.DestroyCity("A"))
+	require.Nil(t, ctrl.MoveAlienToNextCity(app.State.Aliens[2]))
+
+	assert.Len(t, state.WorldMap.Cities, 4)
+	assert.Len(t, state.Aliens, 4)
+	assert.Contains(t, state.WorldMap.Cities["B"].Neighbours, "south")
+	assert.Equal(t, "C", state.Aliens[2].CurrentCity.Name)
+	assert.Equal(t, 0, state.Aliens[2].Moved)
+	assert.Empty(t, state.DestroyedCities)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// funnelAppCfg is a line of cities A - B - C from west to east
+// with alien 0 in A and alien 1 in C, both can only move to B
+var funnelAppCfg = &DummyAppConfig{
+	AlienCount: 2,
+	MaxMoves:   500,
+	Map: map[string][]interface{}{
+		"A": {
+			map[string]string{"east": "B"},
+		},
+		"B": {
+			map[string]string{"west": "A"},
+			map[string]string{"east": "C"},
+		},
+		"C": {
+			map[string]string{"west": "B"},
+		},
+	},
+	AlienLocations: map[string][]int{
+		"A": {0},
+		"C": {1},
+	},
+}
+
+func TestApp_Step(t *testing.T) {
+	app := NewDummyApp(funnelAppCfg)
+
+	// Both aliens move to B
+	report, err := app.Step()
+	require.Nil(t, err)
+	assert.Equal(t, 1, report.Tick)
+	assert.Equal(t, 2, report.Moves)
+	assert.False(t, report.Ended())
+	assert.Equal(t, simulation.EventTickEnded, report.Events[len(report.Events)-1].Type)
+	assert.Equal(t, "B", app.State.Aliens[0].CurrentCity.Name)
+	assert.Equal(t, "B", app.State.Aliens[1].CurrentCity.Name)
+
+	// and destroy it
+	report, err = app.Step()
+	require.Nil(t, err)
+	assert.Equal(t, 2, report.Tick)
+	assert.Equal(t, 0, report.Moves)
+	assert.Equal(t, []string{"B"}, report.DestroyedCities)
+	assert.Equal(t, []int{0, 1}, report.DestroyedAliens)
+	assert.False(t, report.Ended())
+
+	// The simulation is over, it ends without ticking
+	report, err = app.Step()
+	require.Nil(t, err)
+	assert.Equal(t, 2, report.Tick)
+	assert.True(t, report.Ended())
+	assert.Equal(t, simulation.ReasonAliensDestroyed, report.Reason)
+
+	_, err = app.Step()
+	assert.ErrorIs(t, err, simulation.ErrSimulationEnded)
+	assert.Equal(t, simulation.ReasonAliensDestroyed, app.Result().Reason)
+}
+
+func TestApp_RunUntil(t *testing.T) {
+	t.Run("predicate", func(t *testing.T) {
+		app := NewDummyApp(funnelAppCfg)
+
+		report, err := app.RunUntil(func(r simulation.TickReport) bool {
+			return r.Moves > 0
+		})
+		require.Nil(t, err)
+		assert.Equal(t, 1, report.Tick)
+		assert.Len(t, app.State.AlienLocations[app.State.WorldMap.Cities["B"]], 2)
+
+		// the simulation goes on afterwards
+		report, err = app.Step()
+		require.Nil(t, err)
+		assert.Equal(t, []string{"B"}, report.DestroyedCities)
+	})
+
+	t.Run("end", func(t *testing.T) {
+		app := NewDummyApp(funnelAppCfg)
+
+		report, err := app.RunUntil(func(simulation.TickReport) bool { return false })
+		require.Nil(t, err)
+		assert.True(t, report.Ended())
+		assert.Equal(t, simulation.ReasonAliensDestroyed, report.Reason)
+		assert.Equal(t, 2, app.Result().Ticks)
+	})
+}
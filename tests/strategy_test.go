@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"math/rand"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lineAppCfg is a line of cities A - B - C - D - E from west to east
+// with alien 0 in C and alien 1 in E
+var lineAppCfg = &DummyAppConfig{
+	AlienCount: 2,
+	MaxMoves:   500,
+	Map: map[string][]interface{}{
+		"A": {
+			map[string]string{"east": "B"},
+		},
+		"B": {
+			map[string]string{"west": "A"},
+			map[string]string{"east": "C"},
+		},
+		"C": {
+			map[string]string{"west": "B"},
+			map[string]string{"east": "D"},
+		},
+		"D": {
+			map[string]string{"west": "C"},
+			map[string]string{"east": "E"},
+		},
+		"E": {
+			map[string]string{"west": "D"},
+		},
+	},
+	AlienLocations: map[string][]int{
+		"C": {0},
+		"E": {1},
+	},
+}
+
+func TestParseStrategy(t *testing.T) {
+	for _, spec := range []string{"uniform", "seek", "avoid", "weighted:north=2,east=0.5", "lazy:0.3", "lazy:0.5:seek"} {
+		strategy, err := simulation.ParseStrategy(spec)
+		require.Nil(t, err, spec)
+		assert.Equal(t, spec, strategy.String())
+	}
+
+	strategy, err := simulation.ParseStrategy("")
+	require.Nil(t, err)
+	assert.Equal(t, simulation.UniformStrategy{}, strategy)
+
+	for _, spec := range []string{"teleport", "weighted:north", "weighted:north=-1", "weighted:north=0,south=0,east=0,west=0", "lazy:1", "lazy:abc", "lazy:0.5:teleport"} {
+		_, err := simulation.ParseStrategy(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestParseAlienStrategies(t *testing.T) {
+	strategies, err := simulation.ParseAlienStrategies([]string{"0=seek", "3=weighted:north=2,south=1"})
+	require.Nil(t, err)
+	assert.Equal(t, simulation.SeekStrategy{}, strategies[0])
+	assert.Equal(t, "weighted:north=2,south=1", strategies[3].String())
+
+	_, err = simulation.ParseAlienStrategies([]string{"seek"})
+	assert.NotNil(t, err)
+	_, err = simulation.ParseAlienStrategies([]string{"x=seek"})
+	assert.NotNil(t, err)
+}
+
+func TestStrategies_NextCity(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	t.Run("weighted", func(t *testing.T) {
+		app := NewDummyApp(lineAppCfg)
+		c := app.State.WorldMap.Cities["C"]
+		strategy := simulation.WeightedStrategy{Weights: map[string]float64{"west": 0}}
+
+		for i := 0; i < 50; i++ {
+			next, err := strategy.NextCity(app.StateController(), c, rng)
+			require.Nil(t, err)
+			assert.Equal(t, "D", next.Name)
+		}
+
+		// every road is forbidden
+		strategy = simulation.WeightedStrategy{Weights: map[string]float64{"west": 0, "east": 0}}
+		next, err := strategy.NextCity(app.StateController(), c, rng)
+		require.Nil(t, err)
+		assert.Nil(t, next)
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		app := NewDummyApp(lineAppCfg)
+		c := app.State.WorldMap.Cities["C"]
+
+		for i := 0; i < 50; i++ {
+			next, err := simulation.SeekStrategy{}.NextCity(app.StateController(), c, rng)
+			require.Nil(t, err)
+			assert.Equal(t, "D", next.Name)
+		}
+	})
+
+	t.Run("avoid", func(t *testing.T) {
+		app := NewDummyApp(lineAppCfg)
+		d := app.State.WorldMap.Cities["D"]
+
+		for i := 0; i < 50; i++ {
+			next, err := simulation.AvoidCrowdedStrategy{}.NextCity(app.StateController(), d, rng)
+			require.Nil(t, err)
+			// C holds alien 0 and E holds alien 1, both are as crowded
+			assert.Contains(t, []string{"C", "E"}, next.Name)
+		}
+
+		app.State.Aliens[2] = &simulation.Alien{ID: 2, CurrentCity: app.State.WorldMap.Cities["E"]}
+		app.State.AlienLocations[app.State.WorldMap.Cities["E"]][2] = app.State.Aliens[2]
+		for i := 0; i < 50; i++ {
+			next, err := simulation.AvoidCrowdedStrategy{}.NextCity(app.StateController(), d, rng)
+			require.Nil(t, err)
+			assert.Equal(t, "C", next.Name)
+		}
+	})
+
+	t.Run("lazy", func(t *testing.T) {
+		app := NewDummyApp(lineAppCfg)
+		c := app.State.WorldMap.Cities["C"]
+
+		stayed := 0
+		strategy := simulation.LazyStrategy{StayChance: 0.5, Base: simulation.UniformStrategy{}}
+		for i := 0; i < 200; i++ {
+			next, err := strategy.NextCity(app.StateController(), c, rng)
+			require.Nil(t, err)
+			if next == nil {
+				stayed++
+			}
+		}
+		assert.Greater(t, stayed, 0)
+		assert.Less(t, stayed, 200)
+	})
+
+	t.Run("trapped", func(t *testing.T) {
+		app := NewDummyApp(lineAppCfg)
+		isolated := &simulation.City{Name: "Z", Neighbours: map[string]*simulation.City{}}
+
+		for _, strategy := range []simulation.MovementStrategy{
+			simulation.UniformStrategy{},
+			simulation.WeightedStrategy{},
+			simulation.SeekStrategy{},
+			simulation.AvoidCrowdedStrategy{},
+			simulation.LazyStrategy{Base: simulation.UniformStrategy{}},
+		} {
+			_, err := strategy.NextCity(app.StateController(), isolated, rng)
+			require.ErrorIs(t, err, simulation.ErrNoNeighbours, strategy.String())
+		}
+	})
+}
+
+func TestStateCtrl_MoveAlienToNextCity_Strategy(t *testing.T) {
+	app := NewDummyApp(lineAppCfg)
+	ctrl := app.StateController()
+
+	// alien 0 only goes east by default
+	ctrl.SetStrategy(simulation.WeightedStrategy{Weights: map[string]float64{"west": 0}})
+	require.Nil(t, ctrl.MoveAlienToNextCity(app.State.Aliens[0]))
+	assert.Equal(t, "D", app.State.Aliens[0].CurrentCity.Name)
+	assert.Equal(t, 1, app.State.Aliens[0].Moved)
+
+	// alien 1 has its own strategy and never leaves
+	app.State.Aliens[1].Strategy = simulation.WeightedStrategy{Weights: map[string]float64{"west": 0}}
+	require.Nil(t, ctrl.MoveAlienToNextCity(app.State.Aliens[1]))
+	assert.Equal(t, "E", app.State.Aliens[1].CurrentCity.Name)
+	assert.Equal(t, 0, app.State.Aliens[1].Moved)
+}
+
+func TestStateCtrl_IsStuck(t *testing.T) {
+	app := NewDummyApp(lineAppCfg)
+	ctrl := app.StateController()
+
+	// alien 0 only goes west and ends up stuck in A, alien 1 is stuck in E right away
+	app.State.Aliens[0].Strategy = simulation.WeightedStrategy{Weights: map[string]float64{"east": 0}}
+	app.State.Aliens[1].Strategy = simulation.WeightedStrategy{Weights: map[string]float64{"west": 0}}
+	assert.False(t, ctrl.IsStuck(app.State.Aliens[0]))
+	assert.True(t, ctrl.IsStuck(app.State.Aliens[1]))
+	assert.False(t, ctrl.AreRemainingAliensTrapped())
+
+	report, err := app.RunUntil(func(simulation.TickReport) bool { return false })
+	require.Nil(t, err)
+	assert.Equal(t, simulation.ReasonAliensTrapped, report.Reason)
+	assert.Equal(t, "A", app.State.Aliens[0].CurrentCity.Name)
+	assert.Equal(t, 2, app.State.Aliens[0].Moved)
+
+	// lazy aliens are stuck along with their base strategy
+	lazy := simulation.LazyStrategy{StayChance: 0.5, Base: simulation.WeightedStrategy{Weights: map[string]float64{"east": 0}}}
+	assert.False(t, lazy.CanLeave(app.State.WorldMap.Cities["A"]))
+	assert.True(t, lazy.CanLeave(app.State.WorldMap.Cities["E"]))
+}
+
+func TestApp_InitWithConfig_Strategies(t *testing.T) {
+	cfg := simulation.AppCfg{
+		NumAliens:       2,
+		MaxMoves:        10,
+		MapInputFile:    "testdata/test_map.txt",
+		Strategy:        "avoid",
+		AlienStrategies: []string{"1=lazy:0.5:seek"},
+	}
+
+	app, err := simulation.NewAppFromConfig(cfg)
+	require.Nil(t, err)
+	assert.Equal(t, simulation.AvoidCrowdedStrategy{}, app.StateController().StrategyFor(app.State.Aliens[0]))
+	assert.Equal(t, "lazy:0.5:seek", app.StateController().StrategyFor(app.State.Aliens[1]).String())
+
+	cfg.AlienStrategies = []string{"2=seek"}
+	_, err = simulation.NewAppFromConfig(cfg)
+	assert.NotNil(t, err)
+
+	cfg.AlienStrategies = nil
+	cfg.Strategy = "teleport"
+	_, err = simulation.NewAppFromConfig(cfg)
+	assert.NotNil(t, err)
+}
@@ -11,6 +11,7 @@ type DummyAppConfig struct {
 	MaxMoves       int
 	Map            map[string][]interface{} // [cityName, [{direction, neighbour1}, {direction, neighbour2}, ...]
 	AlienLocations map[string][]int
+	Rules          simulation.CollisionRules // zero value applies the default rules
 }
 
 // NewDummyApp(cfg *DummyAppConfig) *app.App creates a dummy app for testing.
@@ -18,6 +19,7 @@ func NewDummyApp(cfg *DummyAppConfig) *simulation.App {
 	app := simulation.NewApp()
 
 	app.Cfg.MaxMoves = cfg.MaxMoves
+	app.Cfg.Rules = cfg.Rules
 	app.State = &simulation.AppState{
 		Aliens:         make(simulation.AlienSet),
 		AlienLocations: make(map[*simulation.City]simulation.AlienSet),
@@ -27,6 +29,7 @@ func NewDummyApp(cfg *DummyAppConfig) *simulation.App {
 	for i := 0; i < cfg.AlienCount; i++ {
 		app.State.Aliens[i] = &simulation.Alien{ID: i, Moved: 0}
 	}
+	app.State.NextAlienID = cfg.AlienCount
 
 	for city := range cfg.Map {
 		app.State.WorldMap.Cities[city] = &simulation.City{Name: city, Neighbours: make(map[string]*simulation.City)}
@@ -66,6 +69,7 @@ func NewEmptyDummyApp() *simulation.App {
 		Cfg: &simulation.AppCfg{},
 	}
 
+	app.SetSeed(1)
 	app.SetStateController(simulation.NewStateController(app))
 	app.SetIOController(simulation.NewIOController(app))
 	app.SetLogger(logger.NewStdoutLogger())
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roadAppCfg is a single road between A and B
+// with alien 0 in A and alien 1 in B
+var roadAppCfg = &DummyAppConfig{
+	AlienCount: 2,
+	MaxMoves:   500,
+	Map: map[string][]interface{}{
+		"A": {
+			map[string]string{"east": "B"},
+		},
+		"B": {
+			map[string]string{"west": "A"},
+		},
+	},
+	AlienLocations: map[string][]int{
+		"A": {0},
+		"B": {1},
+	},
+}
+
+func TestParseTickMode(t *testing.T) {
+	mode, err := simulation.ParseTickMode("")
+	require.Nil(t, err)
+	assert.Equal(t, simulation.TickSequential, mode)
+
+	mode, err = simulation.ParseTickMode("simultaneous")
+	require.Nil(t, err)
+	assert.Equal(t, simulation.TickSimultaneous, mode)
+
+	_, err = simulation.ParseTickMode("turn_based")
+	assert.NotNil(t, err)
+}
+
+func TestMoveAliens_Sequential(t *testing.T) {
+	app := NewDummyApp(roadAppCfg)
+
+	require.Nil(t, app.StateController().MoveAliens())
+	assert.Equal(t, "B", app.State.Aliens[0].CurrentCity.Name)
+	assert.Equal(t, "A", app.State.Aliens[1].CurrentCity.Name)
+}
+
+func TestMoveAliens_Simultaneous(t *testing.T) {
+	app := NewDummyApp(roadAppCfg)
+	app.Cfg.TickMode = simulation.TickSimultaneous
+
+	moves, err := app.StateController().PlanMoves()
+	require.Nil(t, err)
+	require.Len(t, moves, 2)
+	assert.Equal(t, "A", moves[0].From.Name)
+	assert.Equal(t, "B", moves[0].To.Name)
+
+	// Planning leaves the state untouched
+	assert.Equal(t, "A", app.State.Aliens[0].CurrentCity.Name)
+	assert.Equal(t, 0, app.State.Aliens[0].Moved)
+
+	// Without road encounters the aliens swap cities without meeting
+	require.Nil(t, app.StateController().MoveAliens())
+	assert.Len(t, app.State.Aliens, 2)
+	assert.Equal(t, "B", app.State.Aliens[0].CurrentCity.Name)
+	assert.Equal(t, "A", app.State.Aliens[1].CurrentCity.Name)
+	assert.Equal(t, 1, app.State.Aliens[0].Moved)
+}
+
+func TestMoveAliens_RoadEncounters(t *testing.T) {
+	app := NewDummyApp(roadAppCfg)
+	app.Cfg.TickMode = simulation.TickSimultaneous
+	app.Cfg.RoadEncounters = true
+
+	require.Nil(t, app.StateController().MoveAliens())
+	require.Len(t, app.State.Aliens, 1)
+
+	// The winner carries on its move
+	for _, alien := range app.State.Aliens {
+		assert.Equal(t, 1, alien.Moved)
+		if alien.ID == 0 {
+			assert.Equal(t, "B", alien.CurrentCity.Name)
+		} else {
+			assert.Equal(t, "A", alien.CurrentCity.Name)
+		}
+	}
+	assert.Empty(t, app.State.DestroyedCities)
+}
+
+func TestAppCfg_Validate_TickMode(t *testing.T) {
+	cfg := simulation.AppCfg{MaxMoves: 10, MapInputFile: "testdata/test_map.txt", TickMode: "turn_based"}
+	assert.NotNil(t, cfg.Validate())
+
+	cfg.TickMode = simulation.TickSequential
+	cfg.RoadEncounters = true
+	assert.NotNil(t, cfg.Validate())
+
+	cfg.TickMode = simulation.TickSimultaneous
+	assert.Nil(t, cfg.Validate())
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"math/rand"
 	"testing"
 
 	simulation "github.com/derrandz/xtinvasion/pkg"
@@ -9,8 +10,20 @@ import (
 )
 
 func TestGetRandomNeighbor(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	t.Run("nil random source", func(t *testing.T) {
+		city := &simulation.City{Name: "A", Neighbours: map[string]*simulation.City{"north": {Name: "B"}}}
+
+		neighbour, err := simulation.GetRandomNeighbor(nil, city)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "random source is nil")
+
+		assert.Nil(t, neighbour)
+	})
+
 	t.Run("nil city", func(t *testing.T) {
-		neighbour, err := simulation.GetRandomNeighbor(nil)
+		neighbour, err := simulation.GetRandomNeighbor(rng, nil)
 		require.NotNil(t, err)
 		require.Contains(t, err.Error(), "city is nil")
 
@@ -23,7 +36,7 @@ func TestGetRandomNeighbor(t *testing.T) {
 			"B": nil,
 		}
 
-		neighbour, err := simulation.GetRandomNeighbor(city)
+		neighbour, err := simulation.GetRandomNeighbor(rng, city)
 		require.NotNil(t, err)
 		require.Contains(t, err.Error(), " has a nil neighbour")
 
@@ -34,7 +47,7 @@ func TestGetRandomNeighbor(t *testing.T) {
 		city := &simulation.City{Name: "A", Neighbours: make(map[string]*simulation.City)}
 		city.Neighbours = map[string]*simulation.City{}
 
-		neighbour, err := simulation.GetRandomNeighbor(city)
+		neighbour, err := simulation.GetRandomNeighbor(rng, city)
 		require.NotNil(t, err)
 		require.Contains(t, err.Error(), "GetRandomNeighbor: city has no neighbours")
 
@@ -49,13 +62,33 @@ func TestGetRandomNeighbor(t *testing.T) {
 			"D": {Name: "D"},
 		}
 
-		neighbour, err := simulation.GetRandomNeighbor(city)
+		neighbour, err := simulation.GetRandomNeighbor(rng, city)
 		require.Nil(t, err)
 
 		assert.NotNil(t, neighbour)
 		assert.Contains(t, []string{"B", "C", "D"}, neighbour.Name)
 	})
 
+	t.Run("same seed picks the same neighbours", func(t *testing.T) {
+		city := &simulation.City{Name: "A", Neighbours: map[string]*simulation.City{
+			"north": {Name: "B"},
+			"south": {Name: "C"},
+			"east":  {Name: "D"},
+			"west":  {Name: "E"},
+		}}
+
+		rng1 := rand.New(rand.NewSource(42))
+		rng2 := rand.New(rand.NewSource(42))
+		for i := 0; i < 100; i++ {
+			n1, err := simulation.GetRandomNeighbor(rng1, city)
+			require.Nil(t, err)
+			n2, err := simulation.GetRandomNeighbor(rng2, city)
+			require.Nil(t, err)
+
+			assert.Equal(t, n1.Name, n2.Name)
+		}
+	})
+
 }
 
 func TestOppositeDirection(t *testing.T) {
@@ -102,3 +135,10 @@ func TestRemoveSliceElement(t *testing.T) {
 		assert.Equal(t, []int{1, 3}, slice)
 	})
 }
+
+func TestSortDirections(t *testing.T) {
+	directions := []string{"west", "up", "north", "east", "down", "south"}
+	simulation.SortDirections(directions)
+
+	assert.Equal(t, []string{"north", "south", "east", "west", "down", "up"}, directions)
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	simulation "github.com/derrandz/xtinvasion/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMap(t *testing.T) {
+	t.Run("valid map", func(t *testing.T) {
+		diagnostics, err := simulation.ValidateMapFile("testdata/test_map.txt")
+		require.Nil(t, err)
+		assert.Empty(t, diagnostics)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := simulation.ValidateMapFile("nofile.txt")
+		require.NotNil(t, err)
+	})
+
+	// test_invalid_map.txt contains
+	// A north=B east=C
+	// B south=A north=A
+	//
+	// C west=A up=B
+	// A south=C
+	// D north=D south=Z east
+	// =X
+	// E west=A
+	t.Run("invalid map", func(t *testing.T) {
+		diagnostics, err := simulation.ValidateMapFile("testdata/test_invalid_map.txt")
+		require.Nil(t, err)
+		assert.True(t, simulation.HasErrors(diagnostics))
+
+		expected := []struct {
+			line, col int
+			severity  simulation.Severity
+			message   string
+		}{
+			{2, 11, simulation.SeverityError, "linked by more than one road"},
+			{3, 1, simulation.SeverityWarning, "blank line"},
+			{4, 10, simulation.SeverityError, `unknown direction "up"`},
+			{5, 1, simulation.SeverityError, "city A is already defined at line 1"},
+			{5, 3, simulation.SeverityError, "A would have both B and C to its south"},
+			{5, 3, simulation.SeverityError, "linked by more than one road"},
+			{6, 3, simulation.SeverityError, "city D is its own north neighbour"},
+			{6, 11, simulation.SeverityError, "neighbour city Z is not defined"},
+			{6, 19, simulation.SeverityError, `invalid neighbour "east"`},
+			{7, 1, simulation.SeverityError, "line must start with a city name"},
+			{8, 3, simulation.SeverityError, "A would have both C and E to its east"},
+		}
+
+		require.Len(t, diagnostics, len(expected))
+		for i, e := range expected {
+			d := diagnostics[i]
+			assert.Equal(t, "testdata/test_invalid_map.txt", d.File)
+			assert.Equal(t, e.line, d.Line, d.String())
+			assert.Equal(t, e.col, d.Col, d.String())
+			assert.Equal(t, e.severity, d.Severity, d.String())
+			assert.Contains(t, d.Message, e.message)
+		}
+	})
+
+	t.Run("warnings only", func(t *testing.T) {
+		diagnostics, err := simulation.ValidateMap(strings.NewReader("A north=B\n\nB\n"), "map.txt")
+		require.Nil(t, err)
+		require.Len(t, diagnostics, 1)
+		assert.False(t, simulation.HasErrors(diagnostics))
+		assert.Equal(t, "map.txt:2:1: warning: blank line", diagnostics[0].String())
+	})
+}
+
+func TestIOController_ReadMapFromFile_Strict(t *testing.T) {
+	t.Run("strict", func(t *testing.T) {
+		app := NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "testdata/test_invalid_map.txt"
+		app.Cfg.Strict = true
+
+		err := app.IOController().ReadMapFromFile()
+		require.NotNil(t, err)
+
+		var validationErr *simulation.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Diagnostics, 11)
+		assert.Contains(t, err.Error(), "testdata/test_invalid_map.txt:4:10: error: unknown direction \"up\"")
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		app := NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "testdata/test_invalid_map.txt"
+
+		err := app.IOController().ReadMapFromFile()
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "testdata/test_invalid_map.txt:6:")
+	})
+
+	t.Run("lenient unknown direction", func(t *testing.T) {
+		app := NewEmptyDummyApp()
+		app.Cfg.MapInputFile = "testdata/test_unknown_direction_map.txt"
+
+		require.Nil(t, app.IOController().ReadMapFromFile())
+		assert.Equal(t, "B", app.State.WorldMap.Cities["A"].Neighbours["up"].Name)
+		_, found := app.State.WorldMap.Cities["B"].Neighbours[""]
+		assert.False(t, found)
+	})
+}